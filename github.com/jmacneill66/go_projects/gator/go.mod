@@ -5,4 +5,7 @@ go 1.24.1
 require (
 	github.com/google/uuid v1.6.0
 	github.com/lib/pq v1.10.9
+	golang.org/x/net v0.43.0
 )
+
+require golang.org/x/text v0.28.0 // indirect
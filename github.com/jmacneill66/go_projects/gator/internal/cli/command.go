@@ -1,6 +1,11 @@
 package cli
 
-import "fmt"
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+)
 
 // Command represents a CLI command with a name and arguments.
 type Command struct {
@@ -10,22 +15,48 @@ type Command struct {
 
 // Commands holds a map of command names to handler functions.
 type Commands struct {
-	handlers map[string]func(*State, Command) error
+	handlers map[string]func(context.Context, *State, Command) error
 }
 
 // Register registers a command with its handler function.
-func (c *Commands) Register(name string, f func(*State, Command) error) {
+func (c *Commands) Register(name string, f func(context.Context, *State, Command) error) {
 	if c.handlers == nil {
-		c.handlers = make(map[string]func(*State, Command) error)
+		c.handlers = make(map[string]func(context.Context, *State, Command) error)
 	}
 	c.handlers[name] = f
 }
 
 // Run executes a command if it exists.
-func (c *Commands) Run(s *State, cmd Command) error {
+// The context is cancelled when the process is asked to stop.
+func (c *Commands) Run(ctx context.Context, s *State, cmd Command) error {
 	handler, exists := c.handlers[cmd.Name]
 	if !exists {
 		return fmt.Errorf("unknown command: %s", cmd.Name)
 	}
-	return handler(s, cmd)
+	return handler(ctx, s, cmd)
+}
+
+// newFlagSet creates a flag set for a command that reports errors instead
+// of printing them.
+func newFlagSet(command string) *flag.FlagSet {
+	fs := flag.NewFlagSet(command, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	return fs
+}
+
+// parseArgs parses the flags in args, which may come before, between or
+// after the positional arguments, and returns the positional arguments.
+func parseArgs(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
 }
@@ -0,0 +1,91 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/jmacneill66/go_projects/gator/internal/database"
+	"github.com/jmacneill66/go_projects/gator/internal/rss"
+)
+
+// discoverFeedURL resolves a website or feed URL to a single feed URL,
+// asking the user to pick one when the page advertises several feeds.
+func discoverFeedURL(ctx context.Context, s *State, pageURL string) (string, error) {
+	// Only web pages can advertise feeds; other URLs such as file:// are
+	// taken to be feeds
+	if u, err := url.Parse(pageURL); err == nil && u.Scheme != "http" && u.Scheme != "https" {
+		return pageURL, nil
+	}
+
+	links, err := s.Discoverer.Discover(ctx, pageURL)
+	if err != nil {
+		return "", fmt.Errorf("failed to discover feed: %w", err)
+	}
+
+	link, err := chooseFeedLink(links)
+	if err != nil {
+		return "", err
+	}
+	if link.URL != pageURL {
+		fmt.Printf("🔎 Found feed: %s\n", link.URL)
+	}
+	return link.URL, nil
+}
+
+// chooseFeedLink returns the only candidate, or lists the candidates and
+// reads the user's choice from stdin.
+func chooseFeedLink(links []rss.FeedLink) (rss.FeedLink, error) {
+	if len(links) == 0 {
+		return rss.FeedLink{}, rss.ErrNoFeedFound
+	}
+	if len(links) == 1 {
+		return links[0], nil
+	}
+
+	fmt.Println("\n🔎 Multiple feeds found:")
+	for i, link := range links {
+		if link.Title != "" {
+			fmt.Printf("%d) %s\n   %s\n", i+1, link.Title, link.URL)
+		} else {
+			fmt.Printf("%d) %s\n", i+1, link.URL)
+		}
+	}
+
+	var choice string
+	fmt.Printf("Pick a feed [1-%d]: ", len(links))
+	fmt.Scanln(&choice)
+
+	n, err := strconv.Atoi(strings.TrimSpace(choice))
+	if err != nil || n < 1 || n > len(links) {
+		return rss.FeedLink{}, errors.New("invalid choice; no feed selected")
+	}
+	return links[n-1], nil
+}
+
+// findDiscoveredFeed looks up the stored feeds advertised by a website URL,
+// for commands that take the URL of an existing feed.
+func findDiscoveredFeed(ctx context.Context, s *State, pageURL string) (database.GetFeedByUrlRow, error) {
+	links, err := s.Discoverer.Discover(ctx, pageURL)
+	if err != nil {
+		return database.GetFeedByUrlRow{}, err
+	}
+
+	// Only offer feeds that have already been added
+	var known []rss.FeedLink
+	for _, link := range links {
+		if _, err := s.DB.GetFeedByUrl(ctx, link.URL); err == nil {
+			known = append(known, link)
+		}
+	}
+
+	link, err := chooseFeedLink(known)
+	if err != nil {
+		return database.GetFeedByUrlRow{}, err
+	}
+	fmt.Printf("🔎 Found feed: %s\n", link.URL)
+	return s.DB.GetFeedByUrl(ctx, link.URL)
+}
@@ -0,0 +1,142 @@
+package cli
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"regexp"
+	"sync"
+	"testing"
+)
+
+// fakeDB is a database/sql driver that answers the generated queries by
+// their sqlc name, so handlers can be tested without a Postgres server.
+// Queries without a handler succeed and return no rows.
+type fakeDB struct {
+	mu       sync.Mutex
+	handlers map[string]fakeQuery
+	calls    []string
+}
+
+// fakeQuery answers a query given its arguments, returning its columns and
+// rows. The rows of an :exec query are ignored.
+type fakeQuery func(args []driver.Value) ([]string, [][]driver.Value, error)
+
+// queryName matches the sqlc name at the start of a generated query.
+var queryName = regexp.MustCompile(`^-- name: (\w+)`)
+
+// newFakeDB returns a fake database and a connection to it.
+func newFakeDB(t *testing.T) (*fakeDB, *sql.DB) {
+	t.Helper()
+	f := &fakeDB{handlers: map[string]fakeQuery{}}
+	db := sql.OpenDB(f)
+	t.Cleanup(func() { db.Close() })
+	return f, db
+}
+
+// handle sets the handler of the query with the given name.
+func (f *fakeDB) handle(name string, query fakeQuery) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.handlers[name] = query
+}
+
+// called returns how many times the query with the given name ran.
+func (f *fakeDB) called(name string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	n := 0
+	for _, call := range f.calls {
+		if call == name {
+			n++
+		}
+	}
+	return n
+}
+
+// run records a query and answers it with its handler.
+func (f *fakeDB) run(query string, args []driver.NamedValue) ([]string, [][]driver.Value, error) {
+	name := query
+	if m := queryName.FindStringSubmatch(query); m != nil {
+		name = m[1]
+	}
+	values := make([]driver.Value, len(args))
+	for i, arg := range args {
+		values[i] = arg.Value
+	}
+
+	f.mu.Lock()
+	f.calls = append(f.calls, name)
+	handler := f.handlers[name]
+	f.mu.Unlock()
+	if handler == nil {
+		return nil, nil, nil
+	}
+	return handler(values)
+}
+
+func (f *fakeDB) Connect(context.Context) (driver.Conn, error) { return fakeConn{f}, nil }
+func (f *fakeDB) Driver() driver.Driver                        { return fakeDriver{f} }
+
+type fakeDriver struct{ db *fakeDB }
+
+func (d fakeDriver) Open(string) (driver.Conn, error) { return fakeConn{d.db}, nil }
+
+type fakeConn struct{ db *fakeDB }
+
+func (c fakeConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("fakeDB does not prepare statements")
+}
+func (c fakeConn) Close() error              { return nil }
+func (c fakeConn) Begin() (driver.Tx, error) { return fakeTx{c.db}, nil }
+
+func (c fakeConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	columns, rows, err := c.db.run(query, args)
+	if err != nil {
+		return nil, err
+	}
+	return &fakeRows{columns: columns, rows: rows}, nil
+}
+
+func (c fakeConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if _, _, err := c.db.run(query, args); err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(1), nil
+}
+
+type fakeTx struct{ db *fakeDB }
+
+func (tx fakeTx) Commit() error {
+	tx.db.run("COMMIT", nil)
+	return nil
+}
+
+func (tx fakeTx) Rollback() error {
+	tx.db.run("ROLLBACK", nil)
+	return nil
+}
+
+type fakeRows struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string { return r.columns }
+func (r *fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
+
+// fakeRow answers a query with a single row.
+func fakeRow(columns []string, values ...driver.Value) ([]string, [][]driver.Value, error) {
+	return columns, [][]driver.Value{values}, nil
+}
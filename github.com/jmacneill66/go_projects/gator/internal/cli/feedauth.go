@@ -0,0 +1,274 @@
+package cli
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jmacneill66/go_projects/gator/internal/database"
+)
+
+// feedAuth holds the credentials and extra request headers of a feed.
+// Secrets are never stored: SecretEnv names the environment variable that
+// holds the password or token, and header values may reference environment
+// variables as ${NAME}, which all but a few well-known headers must.
+type feedAuth struct {
+	Type      string // "", "basic" or "bearer"
+	Username  string
+	SecretEnv string
+	Headers   map[string]string
+}
+
+// loadFeedAuth decodes the auth columns of a feed.
+func loadFeedAuth(authType, username, secretEnv sql.NullString, headers json.RawMessage) (feedAuth, error) {
+	decoded, err := decodeHeaders(headers)
+	if err != nil {
+		return feedAuth{}, err
+	}
+	return feedAuth{
+		Type:      authType.String,
+		Username:  username.String,
+		SecretEnv: secretEnv.String,
+		Headers:   decoded,
+	}, nil
+}
+
+// decodeHeaders decodes the stored extra headers of a feed.
+func decodeHeaders(raw json.RawMessage) (map[string]string, error) {
+	headers := map[string]string{}
+	if len(raw) > 0 {
+		if err := json.Unmarshal(raw, &headers); err != nil {
+			return nil, fmt.Errorf("invalid stored headers: %w", err)
+		}
+	}
+	return headers, nil
+}
+
+// authLabel describes the auth type of a feed for listings, or returns ""
+// if the feed has none.
+func authLabel(authType sql.NullString) string {
+	switch authType.String {
+	case "basic":
+		return "basic (password from the environment)"
+	case "bearer":
+		return "bearer (token from the environment)"
+	}
+	return authType.String
+}
+
+// requestHeaders returns the headers to send with each fetch of the feed,
+// reading secrets from the environment.
+func (a feedAuth) requestHeaders() (map[string]string, error) {
+	headers := make(map[string]string, len(a.Headers)+1)
+	for name, value := range a.Headers {
+		var missing string
+		headers[name] = envReference.ReplaceAllStringFunc(value, func(ref string) string {
+			key := envReference.FindStringSubmatch(ref)[1]
+			v, ok := os.LookupEnv(key)
+			if !ok {
+				missing = key
+			}
+			return v
+		})
+		if missing != "" {
+			return nil, fmt.Errorf("environment variable %s for header %s is not set", missing, name)
+		}
+	}
+
+	if a.Type == "" {
+		return headers, nil
+	}
+	secret, ok := os.LookupEnv(a.SecretEnv)
+	if !ok {
+		return nil, fmt.Errorf("environment variable %s for %s auth is not set", a.SecretEnv, a.Type)
+	}
+	switch a.Type {
+	case "basic":
+		credentials := base64.StdEncoding.EncodeToString([]byte(a.Username + ":" + secret))
+		headers["Authorization"] = "Basic " + credentials
+	case "bearer":
+		headers["Authorization"] = "Bearer " + secret
+	}
+	return headers, nil
+}
+
+// validate checks that the settings are complete.
+func (a feedAuth) validate() error {
+	switch a.Type {
+	case "":
+		if a.Username != "" || a.SecretEnv != "" {
+			return errors.New("--user and --secret-env need --auth basic or --auth bearer")
+		}
+	case "basic":
+		if a.Username == "" || a.SecretEnv == "" {
+			return errors.New("basic auth needs --user and --secret-env")
+		}
+	case "bearer":
+		if a.SecretEnv == "" {
+			return errors.New("bearer auth needs --secret-env")
+		}
+	default:
+		return fmt.Errorf("unknown auth type %q; use basic, bearer or none", a.Type)
+	}
+	return nil
+}
+
+// isZero reports whether the feed has no credentials or extra headers.
+func (a feedAuth) isZero() bool {
+	return a.Type == "" && len(a.Headers) == 0
+}
+
+// updateParams returns the query parameters that store the settings.
+func (a feedAuth) updateParams(feedID uuid.UUID) (database.UpdateFeedAuthParams, error) {
+	headers, err := json.Marshal(a.Headers)
+	if err != nil {
+		return database.UpdateFeedAuthParams{}, fmt.Errorf("failed to encode headers: %w", err)
+	}
+	return database.UpdateFeedAuthParams{
+		ID:            feedID,
+		AuthType:      sql.NullString{String: a.Type, Valid: a.Type != ""},
+		AuthUsername:  sql.NullString{String: a.Username, Valid: a.Username != ""},
+		AuthSecretEnv: sql.NullString{String: a.SecretEnv, Valid: a.SecretEnv != ""},
+		Headers:       headers,
+	}, nil
+}
+
+// printFeedAuth prints the settings without revealing any secrets.
+func printFeedAuth(a feedAuth) {
+	switch a.Type {
+	case "basic":
+		fmt.Printf("  Auth: basic as %s (password from $%s)\n", a.Username, a.SecretEnv)
+	case "bearer":
+		fmt.Printf("  Auth: bearer (token from $%s)\n", a.SecretEnv)
+	}
+	printHeaderNames(a.Headers)
+}
+
+// printHeaderNames prints the names of a feed's extra headers, but not
+// their values, which may hold secrets.
+func printHeaderNames(headers map[string]string) {
+	if len(headers) == 0 {
+		return
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	fmt.Printf("  Headers: %s\n", strings.Join(names, ", "))
+}
+
+// plainHeaders are the headers, by canonical name, that never carry secrets
+// and so may be stored with a literal value. Any other header could hold a
+// secret under a name we cannot recognize, so its value must come from the
+// environment.
+var plainHeaders = map[string]bool{
+	"Accept":           true,
+	"Accept-Language":  true,
+	"Cache-Control":    true,
+	"From":             true,
+	"Referer":          true,
+	"User-Agent":       true,
+	"X-Requested-With": true,
+}
+
+// envReference matches a reference to an environment variable as ${NAME}.
+// A bare $ is left alone, so literal values like "$5" survive expansion.
+var envReference = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// referencesEnv reports whether a header value references an environment
+// variable as ${NAME}.
+func referencesEnv(value string) bool {
+	return envReference.MatchString(value)
+}
+
+// feedFlags holds the feed settings given as flags to addfeed and editfeed.
+type feedFlags struct {
+	set          map[string]bool
+	auth         string
+	username     string
+	secretEnv    string
+	headers      headerFlags
+	clearHeaders bool
+	minInterval  string
+	maxInterval  string
+	enable       bool
+}
+
+// headerFlags collects repeated --header "Name: value" flags.
+type headerFlags []string
+
+func (h *headerFlags) String() string { return strings.Join(*h, ", ") }
+
+func (h *headerFlags) Set(value string) error {
+	if name, _, ok := strings.Cut(value, ":"); !ok || strings.TrimSpace(name) == "" {
+		return fmt.Errorf("invalid header %q; use \"Name: value\"", value)
+	}
+	*h = append(*h, value)
+	return nil
+}
+
+// parseFeedFlags separates the flags of a feed command from its positional
+// arguments.
+func parseFeedFlags(command string, args []string) ([]string, *feedFlags, error) {
+	flags := &feedFlags{set: map[string]bool{}}
+	fs := newFlagSet(command)
+	fs.StringVar(&flags.auth, "auth", "", "basic, bearer or none")
+	fs.StringVar(&flags.username, "user", "", "user name for basic auth")
+	fs.StringVar(&flags.secretEnv, "secret-env", "", "environment variable holding the password or token")
+	fs.Var(&flags.headers, "header", `extra request header, "Name: value"`)
+	fs.BoolVar(&flags.clearHeaders, "clear-headers", false, "remove all extra headers")
+	fs.StringVar(&flags.minInterval, "min-interval", "", "shortest time between fetches, or default")
+	fs.StringVar(&flags.maxInterval, "max-interval", "", "longest time between fetches, or default")
+	fs.BoolVar(&flags.enable, "enable", false, "fetch a disabled or dead feed again")
+
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return nil, nil, err
+	}
+	fs.Visit(func(f *flag.Flag) { flags.set[f.Name] = true })
+	return positional, flags, nil
+}
+
+// apply changes the settings given by the flags and validates the result.
+// A header with an empty value removes that header.
+func (f *feedFlags) apply(auth *feedAuth) error {
+	if f.set["auth"] {
+		auth.Type = strings.ToLower(f.auth)
+		if auth.Type == "none" {
+			*auth = feedAuth{Headers: auth.Headers}
+		}
+	}
+	if f.set["user"] {
+		auth.Username = f.username
+	}
+	if f.set["secret-env"] {
+		auth.SecretEnv = strings.TrimPrefix(f.secretEnv, "$")
+	}
+
+	if auth.Headers == nil || f.clearHeaders {
+		auth.Headers = map[string]string{}
+	}
+	for _, header := range f.headers {
+		name, value, _ := strings.Cut(header, ":")
+		name, value = http.CanonicalHeaderKey(strings.TrimSpace(name)), strings.TrimSpace(value)
+		switch {
+		case value == "":
+			delete(auth.Headers, name)
+		case !plainHeaders[name] && !referencesEnv(value):
+			return fmt.Errorf("header %s may hold a secret; reference an environment variable instead, as '%s: ${NAME}'", name, name)
+		default:
+			auth.Headers[name] = value
+		}
+	}
+	return auth.validate()
+}
@@ -0,0 +1,117 @@
+package cli
+
+import (
+	"database/sql"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRequestHeaders(t *testing.T) {
+	t.Setenv("GATOR_TEST_TOKEN", "s3cret")
+	t.Setenv("GATOR_TEST_PASSWORD", "hunter2")
+
+	tests := []struct {
+		name    string
+		auth    feedAuth
+		want    map[string]string
+		wantErr string
+	}{
+		{
+			"literal values",
+			feedAuth{Headers: map[string]string{"Accept": "application/rss+xml", "X-Price": "$5 or $x"}},
+			map[string]string{"Accept": "application/rss+xml", "X-Price": "$5 or $x"},
+			"",
+		},
+		{
+			"environment references",
+			feedAuth{Headers: map[string]string{"X-Api-Key": "${GATOR_TEST_TOKEN}", "Cookie": "a=${GATOR_TEST_TOKEN}; b=$GATOR_TEST_TOKEN"}},
+			map[string]string{"X-Api-Key": "s3cret", "Cookie": "a=s3cret; b=$GATOR_TEST_TOKEN"},
+			"",
+		},
+		{
+			"missing variable",
+			feedAuth{Headers: map[string]string{"X-Api-Key": "${GATOR_TEST_MISSING}"}},
+			nil,
+			"GATOR_TEST_MISSING",
+		},
+		{
+			"basic auth",
+			feedAuth{Type: "basic", Username: "ada", SecretEnv: "GATOR_TEST_PASSWORD"},
+			map[string]string{"Authorization": "Basic YWRhOmh1bnRlcjI="},
+			"",
+		},
+		{
+			"bearer auth",
+			feedAuth{Type: "bearer", SecretEnv: "GATOR_TEST_TOKEN"},
+			map[string]string{"Authorization": "Bearer s3cret"},
+			"",
+		},
+		{
+			"missing secret",
+			feedAuth{Type: "bearer", SecretEnv: "GATOR_TEST_MISSING"},
+			nil,
+			"GATOR_TEST_MISSING",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.auth.requestHeaders()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("got error %v, want one naming %s", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFeedFlagsApply(t *testing.T) {
+	tests := []struct {
+		header  string
+		wantErr bool
+	}{
+		{"Accept: application/atom+xml", false},
+		{"user-agent: gator-test", false},
+		{"X-Api-Key: ${NEWS_KEY}", false},
+		{"Authorization: Token ${NEWS_TOKEN}", false},
+		{"Authorization: Token abc123", true},
+		{"X-Api-Key: abc123", true},
+		{"X-Tenant-Credential: abc123", true},
+		{"X-Api-Key: $NEWS_KEY", true},
+		{"X-Api-Key:", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			_, flags, err := parseFeedFlags("editfeed", []string{"--header", tt.header})
+			if err != nil {
+				t.Fatal(err)
+			}
+			auth := feedAuth{}
+			err = flags.apply(&auth)
+			if tt.wantErr != (err != nil) {
+				t.Errorf("got error %v, want error: %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAuthLabel(t *testing.T) {
+	tests := map[string]sql.NullString{
+		"":                                      {},
+		"basic (password from the environment)": {String: "basic", Valid: true},
+		"bearer (token from the environment)":   {String: "bearer", Valid: true},
+	}
+	for want, authType := range tests {
+		if got := authLabel(authType); got != want {
+			t.Errorf("authLabel(%q) = %q, want %q", authType.String, got, want)
+		}
+	}
+}
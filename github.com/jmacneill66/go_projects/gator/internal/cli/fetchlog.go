@@ -0,0 +1,154 @@
+package cli
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmacneill66/go_projects/gator/internal/database"
+)
+
+// fetchLogRetentionDays is how long agg keeps the fetch log.
+const fetchLogRetentionDays = 90
+
+// Default feedstats settings, used when no flags are given
+const (
+	defaultFeedStatsDays  = 30
+	defaultFeedStatsLimit = 10
+)
+
+// logFeedFetch adds an attempt to fetch a feed to the fetch log. Attempts
+// that failed because of a shutdown say nothing about the feed and are
+// left out; those that completed are logged even after ctx is cancelled.
+func logFeedFetch(ctx context.Context, s *State, feedID uuid.UUID, startedAt time.Time, stats fetchStats, fetchErr error) {
+	if fetchErr != nil && ctx.Err() != nil {
+		return
+	}
+	status := statusCode(stats.statusCode)
+	var errText sql.NullString
+	if fetchErr != nil {
+		// Keep the status of a fetch that succeeded but could not be saved
+		if code := errorStatusCode(fetchErr); code.Valid {
+			status = code
+		}
+		errText = sql.NullString{String: fetchErr.Error(), Valid: true}
+	}
+
+	err := s.DB.CreateFeedFetch(context.WithoutCancel(ctx), database.CreateFeedFetchParams{
+		ID:         uuid.New(),
+		FeedID:     feedID,
+		StartedAt:  startedAt,
+		FinishedAt: time.Now(),
+		StatusCode: status,
+		Bytes:      stats.bytes,
+		ItemCount:  int32(stats.items),
+		NewPosts:   int32(stats.newPosts),
+		Error:      errText,
+	})
+	if err != nil {
+		log.Printf("Error logging fetch: %v\n", err)
+	}
+}
+
+// pruneFetchLog deletes fetch log entries older than the retention period.
+func pruneFetchLog(ctx context.Context, s *State) {
+	if err := s.DB.DeleteOldFeedFetches(ctx, fetchLogRetentionDays); err != nil && ctx.Err() == nil {
+		log.Printf("Error pruning fetch log: %v\n", err)
+	}
+}
+
+// HandlerFeedStats reports how fetching has gone for every feed, or in
+// detail for one feed with its most recent fetch attempts.
+func HandlerFeedStats(ctx context.Context, s *State, cmd Command) error {
+	const usage = "usage: feedstats [url] [--days N] [--limit N]"
+	fs := newFlagSet("feedstats")
+	days := fs.Int("days", defaultFeedStatsDays, "number of days to summarise")
+	limit := fs.Int("limit", defaultFeedStatsLimit, "number of recent fetches to list")
+	args, err := parseArgs(fs, cmd.Args)
+	if err != nil {
+		return fmt.Errorf("%w\n%s", err, usage)
+	}
+	if len(args) > 1 {
+		return errors.New(usage)
+	}
+	if *days < 1 || *limit < 1 {
+		return errors.New("days and limit must be positive integers")
+	}
+
+	// Report on one feed when a URL is given, or an old URL it moved from
+	var feedID uuid.NullUUID
+	if len(args) == 1 {
+		feed, err := s.DB.GetFeedByUrl(ctx, args[0])
+		if err != nil {
+			return fmt.Errorf("no feed found with URL: %s", args[0])
+		}
+		feedID = uuid.NullUUID{UUID: feed.ID, Valid: true}
+	}
+
+	stats, err := s.DB.GetFeedFetchStats(ctx, database.GetFeedFetchStatsParams{
+		Days:   int32(*days),
+		FeedID: feedID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to fetch feed stats: %w", err)
+	}
+
+	fmt.Printf("\n=== Feed Stats (last %d days) ===\n", *days)
+	for _, feed := range stats {
+		fmt.Printf("- %s\n  URL: %s\n", feed.Name, feed.Url)
+		if feed.Attempts == 0 {
+			fmt.Println("  Fetches: none")
+		} else {
+			fmt.Printf("  Fetches: %d, %.1f%% succeeded\n", feed.Attempts, 100*float64(feed.Successes)/float64(feed.Attempts))
+			fmt.Printf("  Latency: p50 %s, p90 %s, p99 %s\n",
+				seconds(feed.P50Seconds), seconds(feed.P90Seconds), seconds(feed.P99Seconds))
+		}
+		fmt.Printf("  Posts per day: %.2f\n", float64(feed.PublishedPosts)/float64(*days))
+		if feedID.Valid {
+			if err := printRecentFetches(ctx, s, feed.ID, *limit); err != nil {
+				return err
+			}
+		}
+		fmt.Println()
+	}
+	return nil
+}
+
+// printRecentFetches lists the most recent attempts to fetch a feed.
+func printRecentFetches(ctx context.Context, s *State, feedID uuid.UUID, limit int) error {
+	fetches, err := s.DB.GetRecentFeedFetches(ctx, database.GetRecentFeedFetchesParams{
+		FeedID: feedID,
+		Limit:  int32(limit),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to fetch recent fetches: %w", err)
+	}
+	if len(fetches) == 0 {
+		return nil
+	}
+
+	fmt.Printf("  Last %d fetches:\n", len(fetches))
+	for _, fetch := range fetches {
+		status := "---"
+		if fetch.StatusCode.Valid {
+			status = fmt.Sprint(fetch.StatusCode.Int32)
+		}
+		fmt.Printf("  %s  %s  %6s", fetch.StartedAt.Format(time.RFC822), status,
+			fetch.FinishedAt.Sub(fetch.StartedAt).Round(time.Millisecond))
+		if fetch.Error.Valid {
+			fmt.Printf("  ❌ %s\n", fetch.Error.String)
+			continue
+		}
+		fmt.Printf("  %.1f KB, %d items, %d new\n", float64(fetch.Bytes)/1024, fetch.ItemCount, fetch.NewPosts)
+	}
+	return nil
+}
+
+// seconds formats a latency in seconds as a duration.
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second)).Round(time.Millisecond)
+}
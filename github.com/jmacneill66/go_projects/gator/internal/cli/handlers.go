@@ -2,18 +2,24 @@ package cli
 
 import (
 	"context"
+	"database/sql"
 	"errors"
 	"fmt"
 	"github.com/jmacneill66/go_projects/gator/internal/database"
+	"github.com/jmacneill66/go_projects/gator/internal/opml"
+	"github.com/jmacneill66/go_projects/gator/internal/render"
+	"github.com/jmacneill66/go_projects/gator/internal/rss"
 	"log"
+	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 )
 
 // HandlerLogin handles the "login" command.
-func HandlerLogin(s *State, cmd Command) error {
+func HandlerLogin(ctx context.Context, s *State, cmd Command) error {
 	if len(cmd.Args) == 0 {
 		return errors.New("username is required")
 	}
@@ -21,7 +27,7 @@ func HandlerLogin(s *State, cmd Command) error {
 	username := cmd.Args[0]
 
 	// GetUser
-	user, err := s.DB.GetUser(context.Background(), username)
+	user, err := s.DB.GetUser(ctx, username)
 	if err != nil {
 		fmt.Printf("Error: user '%s' does not exist.\n", username)
 		return fmt.Errorf("user '%s' does not exist", username)
@@ -38,7 +44,7 @@ func HandlerLogin(s *State, cmd Command) error {
 }
 
 // HandlerRegister handles the "register" command.
-func HandlerRegister(s *State, cmd Command) error {
+func HandlerRegister(ctx context.Context, s *State, cmd Command) error {
 	if len(cmd.Args) == 0 {
 		return errors.New("username is required")
 	}
@@ -52,7 +58,7 @@ func HandlerRegister(s *State, cmd Command) error {
 	now := time.Now()
 
 	// Create the user in the database
-	_, err := s.DB.CreateUser(context.Background(), database.CreateUserParams{
+	_, err := s.DB.CreateUser(ctx, database.CreateUserParams{
 		ID:        userID,
 		Name:      username,
 		CreatedAt: now,
@@ -76,7 +82,7 @@ func HandlerRegister(s *State, cmd Command) error {
 }
 
 // HandlerReset deletes all users from the database.
-func HandlerReset(s *State, cmd Command) error {
+func HandlerReset(ctx context.Context, s *State, cmd Command) error {
 	fmt.Println("⚠️  WARNING: This will delete all users from the database!")
 
 	/* Confirm deletion (optional)
@@ -89,7 +95,7 @@ func HandlerReset(s *State, cmd Command) error {
 	}*/
 
 	// Execute the DeleteAllUsers query
-	err := s.DB.DeleteAllUsers(context.Background())
+	err := s.DB.DeleteAllUsers(ctx)
 	if err != nil {
 		fmt.Println("Error: Failed to reset the database.")
 		return fmt.Errorf("failed to delete users: %w", err)
@@ -100,9 +106,9 @@ func HandlerReset(s *State, cmd Command) error {
 }
 
 // HandlerUsers fetches and prints all users.
-func HandlerUsers(s *State, cmd Command) error {
+func HandlerUsers(ctx context.Context, s *State, cmd Command) error {
 	// Get all users from the database
-	users, err := s.DB.GetUsers(context.Background())
+	users, err := s.DB.GetUsers(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to fetch users: %w", err)
 	}
@@ -122,8 +128,14 @@ func HandlerUsers(s *State, cmd Command) error {
 	return nil
 }
 
-// HandlerAgg fetches and prints an RSS feed.
-func HandlerAgg(s *State, cmd Command) error {
+// Default agg settings, used when no flags are given
+const (
+	defaultAggConcurrency = 4
+	defaultAggBatchSize   = 20
+)
+
+// HandlerAgg collects due feeds in batches at a fixed interval.
+func HandlerAgg(ctx context.Context, s *State, cmd Command) error {
 	/*feedURL := "https://www.wagslane.dev/index.xml"
 
 	fmt.Println("Fetching RSS feed...")
@@ -145,30 +157,81 @@ func HandlerAgg(s *State, cmd Command) error {
 	log.Println("RSS feed successfully fetched and parsed.")
 	return nil */
 
+	const usage = "usage: agg <time_between_reqs> [--concurrency N] [--batch N] (e.g., agg 1m --concurrency 8)"
+	fs := newFlagSet("agg")
+	concurrency := fs.Int("concurrency", defaultAggConcurrency, "number of feeds fetched at once")
+	batchSize := fs.Int("batch", defaultAggBatchSize, "number of feeds fetched per cycle")
+	args, err := parseArgs(fs, cmd.Args)
+	if err != nil {
+		return fmt.Errorf("%w\n%s", err, usage)
+	}
+
 	// Ensure interval is provided
-	if len(cmd.Args) < 1 {
-		return errors.New("usage: agg <time_between_reqs> (e.g., 1s, 1m, 1h)")
+	if len(args) < 1 {
+		return errors.New(usage)
+	}
+	if *concurrency < 1 || *batchSize < 1 {
+		return errors.New("concurrency and batch size must be positive integers")
 	}
 	// Parse interval
-	timeBetweenRequests, err := time.ParseDuration(cmd.Args[0])
+	timeBetweenRequests, err := time.ParseDuration(args[0])
 	if err != nil {
 		return fmt.Errorf("invalid duration format: %w", err)
 	}
-	fmt.Printf("⏳ Collecting feeds every %s\n", timeBetweenRequests)
+	// Receive WebSub pushes while collecting, when a callback URL is set
+	if s.Cfg.WebSub.CallbackURL != "" {
+		stopWebSub, err := startWebSub(s)
+		if err != nil {
+			return err
+		}
+		// Let pushes that are being saved finish before agg exits
+		defer func() {
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), webSubShutdownTimeout)
+			defer cancel()
+			if err := stopWebSub(shutdownCtx); err != nil {
+				log.Printf("Error shutting down WebSub listener: %v\n", err)
+			}
+		}()
+	}
+
+	fmt.Printf("⏳ Collecting up to %d feeds every %s, %d at a time\n", *batchSize, timeBetweenRequests, *concurrency)
 	// Create ticker for periodic execution
 	ticker := time.NewTicker(timeBetweenRequests)
 	defer ticker.Stop()
-	// Run immediately, then on each tick
+	// Run immediately, then on each tick until asked to stop
+	var total ScrapeSummary
 	for {
-		ScrapeFeeds(s)
-		<-ticker.C
+		pruneFetchLog(ctx, s)
+		summary, errs := ScrapeFeeds(ctx, s, *concurrency, *batchSize)
+		total.Add(summary)
+		for _, err := range errs {
+			log.Printf("Error fetching feed %v\n", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			fmt.Printf("\n🛑 Shutting down: fetched %d feeds, %d failed, %d interrupted (%d new posts, %d updated)\n",
+				total.Feeds, total.Failed, total.Interrupted, total.NewPosts, total.UpdatedPosts)
+			return nil
+		case <-ticker.C:
+		}
 	}
 }
 
-// HandlerFeeds prints all RSS feeds from the database.
-func HandlerFeeds(s *State, cmd Command) error {
+// HandlerFeeds prints all RSS feeds from the database, or with --broken
+// only those that are failing, disabled or dead.
+func HandlerFeeds(ctx context.Context, s *State, cmd Command) error {
+	fs := newFlagSet("feeds")
+	broken := fs.Bool("broken", false, "only show failing, disabled and dead feeds")
+	if _, err := parseArgs(fs, cmd.Args); err != nil {
+		return fmt.Errorf("%w\nusage: feeds [--broken]", err)
+	}
+	if *broken {
+		return printBrokenFeeds(ctx, s)
+	}
+
 	// Fetch all feeds with user info
-	feeds, err := s.DB.GetFeedsWithUser(context.Background())
+	feeds, err := s.DB.GetFeedsWithUser(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to fetch feeds: %w", err)
 	}
@@ -176,30 +239,54 @@ func HandlerFeeds(s *State, cmd Command) error {
 	// Print feeds
 	fmt.Println("\n=== Feeds ===")
 	for _, feed := range feeds {
-		fmt.Printf("- %s\n  URL: %s\n  Added by: %s\n\n", feed.Name, feed.Url, feed.UserName)
+		fmt.Printf("- %s\n  URL: %s\n", feed.Name, feed.Url)
+		printField("Title", feed.Title)
+		printField("Description", feed.Description)
+		printField("Site", feed.SiteUrl)
+		printField("Language", feed.Language)
+		printField("Image", feed.ImageUrl)
+		printField("Generator", feed.Generator)
+		if label := authLabel(feed.AuthType); label != "" {
+			fmt.Printf("  Auth: %s\n", label)
+		}
+		if headers, err := decodeHeaders(feed.Headers); err == nil {
+			printHeaderNames(headers)
+		}
+		printFetchIntervals(feed.MinFetchIntervalSeconds, feed.MaxFetchIntervalSeconds)
+		if feed.DeadAt.Valid {
+			fmt.Printf("  Dead since: %s (410 Gone)\n", feed.DeadAt.Time.Format(time.RFC822))
+		} else if feed.DisabledAt.Valid {
+			fmt.Printf("  Disabled since: %s (see feeds --broken)\n", feed.DisabledAt.Time.Format(time.RFC822))
+		} else if feed.NextFetchAt.Valid {
+			fmt.Printf("  Next fetch: %s\n", feed.NextFetchAt.Time.Format(time.RFC822))
+		}
+		fmt.Printf("  Added by: %s\n\n", feed.UserName)
 	}
 
 	return nil
 }
 
 // HandlerFollow allows a user to follow a feed.
-func HandlerFollow(s *State, cmd Command, user database.User) error {
+func HandlerFollow(ctx context.Context, s *State, cmd Command, user database.User) error {
 	// Ensure feed URL is provided
 	if len(cmd.Args) < 1 {
 		return errors.New("usage: follow <feed_url>")
 	}
 	feedURL := cmd.Args[0]
 
-	// Get the feed by URL
-	feed, err := s.DB.GetFeedByUrl(context.Background(), feedURL)
+	// Get the feed by URL, falling back to the feeds advertised by a website
+	feed, err := s.DB.GetFeedByUrl(ctx, feedURL)
 	if err != nil {
-		return fmt.Errorf("no feed found with URL: %s", feedURL)
+		feed, err = findDiscoveredFeed(ctx, s, feedURL)
+		if err != nil {
+			return fmt.Errorf("no feed found with URL: %s", feedURL)
+		}
 	}
 	// Create feed follow record
 	followID := uuid.New()
 	now := time.Now()
 
-	follow, err := s.DB.CreateFeedFollow(context.Background(), database.CreateFeedFollowParams{
+	follow, err := s.DB.CreateFeedFollow(ctx, database.CreateFeedFollowParams{
 		ID:        followID,
 		CreatedAt: now,
 		UpdatedAt: now,
@@ -215,9 +302,9 @@ func HandlerFollow(s *State, cmd Command, user database.User) error {
 }
 
 // HandlerFollowing prints all feeds a user is following.
-func HandlerFollowing(s *State, cmd Command, user database.User) error {
+func HandlerFollowing(ctx context.Context, s *State, cmd Command, user database.User) error {
 	// Get the feed follows for the user
-	follows, err := s.DB.GetFeedFollowsForUser(context.Background(), s.Cfg.CurrentUserName)
+	follows, err := s.DB.GetFeedFollowsForUser(ctx, s.Cfg.CurrentUserName)
 	if err != nil {
 		return fmt.Errorf("failed to fetch followed feeds: %w", err)
 	}
@@ -225,23 +312,91 @@ func HandlerFollowing(s *State, cmd Command, user database.User) error {
 	fmt.Println("\n=== Following Feeds ===")
 	for _, follow := range follows {
 		fmt.Printf("- %s\n", follow.FeedName)
+		printField("Title", follow.FeedTitle)
+		printField("Description", follow.FeedDescription)
+		printField("Site", follow.FeedSiteUrl)
 	}
 	return nil
 }
 
+// printField prints an indented label and value, if the value is set.
+func printField(label string, value sql.NullString) {
+	if value.Valid && value.String != "" {
+		fmt.Printf("  %s: %s\n", label, value.String)
+	}
+}
+
 // HandlerAddFeed adds a new RSS feed and follows it.
-func HandlerAddFeed(s *State, cmd Command, user database.User) error {
-	// Ensure name and URL are provided
-	if len(cmd.Args) < 2 {
-		return errors.New("usage: addfeed <name> <url>")
+func HandlerAddFeed(ctx context.Context, s *State, cmd Command, user database.User) error {
+	const usage = "usage: addfeed [name] <url> [--auth basic|bearer --user <name> --secret-env <VAR>] [--header \"Name: value\"]... [--min-interval <dur>] [--max-interval <dur>]"
+	args, flags, err := parseFeedFlags("addfeed", cmd.Args)
+	if err != nil {
+		return fmt.Errorf("%w\n%s", err, usage)
+	}
+
+	// Ensure a URL is provided; the name is optional
+	var feedName, pageURL string
+	switch len(args) {
+	case 1:
+		pageURL = args[0]
+	case 2:
+		feedName, pageURL = args[0], args[1]
+	default:
+		return errors.New(usage)
+	}
+
+	var auth feedAuth
+	if err := flags.apply(&auth); err != nil {
+		return err
+	}
+	var minInterval, maxInterval sql.NullInt32
+	if err := flags.applyIntervals(&minInterval, &maxInterval); err != nil {
+		return err
+	}
+
+	// Resolve website URLs to the feed they advertise. Credentials are for
+	// the feed itself, so a URL given with them is used as is.
+	feedURL := pageURL
+	existing, err := s.DB.GetFeedByUrl(ctx, feedURL)
+	if errors.Is(err, sql.ErrNoRows) && auth.isZero() {
+		feedURL, err = discoverFeedURL(ctx, s, pageURL)
+		if err != nil {
+			return err
+		}
+		existing, err = s.DB.GetFeedByUrl(ctx, feedURL)
+	}
+
+	// A feed known by this URL, or by one it has moved from, is followed
+	// rather than added twice
+	switch {
+	case err == nil:
+		return followExistingFeed(ctx, s, user, existing, flags)
+	case !errors.Is(err, sql.ErrNoRows):
+		return fmt.Errorf("failed to look up feed: %w", err)
+	}
+
+	// Without a name, fetch the feed and use its channel title
+	var rssFeed *rss.RSSFeed
+	if feedName == "" {
+		headers, err := auth.requestHeaders()
+		if err != nil {
+			return err
+		}
+		result, err := s.Fetcher.FetchFeed(ctx, feedURL, rss.FetchOptions{Headers: headers})
+		if err != nil {
+			return fmt.Errorf("failed to fetch feed: %w", err)
+		}
+		rssFeed = result.Feed
+		feedName = strings.TrimSpace(rssFeed.Channel.Title)
+		if feedName == "" {
+			feedName = feedURL
+		}
 	}
-	feedName := cmd.Args[0]
-	feedURL := cmd.Args[1]
 
 	// Create new feed
 	feedID := uuid.New()
 	now := time.Now()
-	feed, err := s.DB.CreateFeed(context.Background(), database.CreateFeedParams{
+	feed, err := s.DB.CreateFeed(ctx, database.CreateFeedParams{
 		ID:        feedID,
 		CreatedAt: now,
 		UpdatedAt: now,
@@ -252,9 +407,33 @@ func HandlerAddFeed(s *State, cmd Command, user database.User) error {
 	if err != nil {
 		return fmt.Errorf("failed to create feed: %w", err)
 	}
+	if !auth.isZero() {
+		params, err := auth.updateParams(feed.ID)
+		if err != nil {
+			return err
+		}
+		if err := s.DB.UpdateFeedAuth(ctx, params); err != nil {
+			return fmt.Errorf("failed to save feed credentials: %w", err)
+		}
+	}
+	if flags.intervalsSet() {
+		err := s.DB.UpdateFeedIntervals(ctx, database.UpdateFeedIntervalsParams{
+			ID:                      feed.ID,
+			MinFetchIntervalSeconds: minInterval,
+			MaxFetchIntervalSeconds: maxInterval,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to save fetch intervals: %w", err)
+		}
+	}
+	if rssFeed != nil {
+		if err := saveFeedMetadata(ctx, s.DB, feed.ID, rssFeed); err != nil {
+			log.Printf("Error saving feed metadata: %v\n", err)
+		}
+	}
 	// Auto-follow the feed
 	followID := uuid.New()
-	_, err = s.DB.CreateFeedFollow(context.Background(), database.CreateFeedFollowParams{
+	_, err = s.DB.CreateFeedFollow(ctx, database.CreateFeedFollowParams{
 		ID:        followID,
 		CreatedAt: now,
 		UpdatedAt: now,
@@ -272,8 +451,97 @@ func HandlerAddFeed(s *State, cmd Command, user database.User) error {
 	return nil
 }
 
+// followExistingFeed follows a feed that addfeed was asked to add again.
+// Its settings belong to whoever added it, so flags are not applied.
+func followExistingFeed(ctx context.Context, s *State, user database.User, feed database.GetFeedByUrlRow, flags *feedFlags) error {
+	now := time.Now()
+	follow, err := s.DB.CreateFeedFollow(ctx, database.CreateFeedFollowParams{
+		ID:        uuid.New(),
+		CreatedAt: now,
+		UpdatedAt: now,
+		UserID:    user.ID,
+		FeedID:    feed.ID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to follow feed: %w", err)
+	}
+	fmt.Printf("✅ Feed already added as '%s'; %s is now following it\n", follow.FeedName, follow.UserName)
+	if len(flags.set) > 0 {
+		fmt.Println("⚠️  Its settings were not changed; use editfeed on a feed you added")
+	}
+	return nil
+}
+
+// HandlerEditFeed changes the credentials, extra request headers and fetch
+// intervals of a feed added by the current user, or re-enables it.
+func HandlerEditFeed(ctx context.Context, s *State, cmd Command, user database.User) error {
+	const usage = "usage: editfeed <url> [--auth basic|bearer|none] [--user <name>] [--secret-env <VAR>] [--header \"Name: value\"]... [--clear-headers] [--min-interval <dur>|default] [--max-interval <dur>|default] [--enable]"
+	args, flags, err := parseFeedFlags("editfeed", cmd.Args)
+	if err != nil {
+		return fmt.Errorf("%w\n%s", err, usage)
+	}
+	if len(args) != 1 || len(flags.set) == 0 {
+		return errors.New(usage)
+	}
+
+	// Get the feed by URL, or by an old URL it has moved from
+	found, err := s.DB.GetFeedByUrl(ctx, args[0])
+	if err != nil {
+		return fmt.Errorf("no feed found with URL: %s", args[0])
+	}
+	feed, err := s.DB.GetFeedSettings(ctx, found.ID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch feed settings: %w", err)
+	}
+	if feed.UserID != user.ID {
+		return errors.New("only the user who added a feed can edit it")
+	}
+
+	auth, err := loadFeedAuth(feed.AuthType, feed.AuthUsername, feed.AuthSecretEnv, feed.Headers)
+	if err != nil {
+		return err
+	}
+	if err := flags.apply(&auth); err != nil {
+		return err
+	}
+	minInterval, maxInterval := feed.MinFetchIntervalSeconds, feed.MaxFetchIntervalSeconds
+	if err := flags.applyIntervals(&minInterval, &maxInterval); err != nil {
+		return err
+	}
+	params, err := auth.updateParams(feed.ID)
+	if err != nil {
+		return err
+	}
+	if err := s.DB.UpdateFeedAuth(ctx, params); err != nil {
+		return fmt.Errorf("failed to update feed: %w", err)
+	}
+	if flags.intervalsSet() {
+		err := s.DB.UpdateFeedIntervals(ctx, database.UpdateFeedIntervalsParams{
+			ID:                      feed.ID,
+			MinFetchIntervalSeconds: minInterval,
+			MaxFetchIntervalSeconds: maxInterval,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to update fetch intervals: %w", err)
+		}
+	}
+	if flags.enable {
+		if err := s.DB.EnableFeed(ctx, feed.ID); err != nil {
+			return fmt.Errorf("failed to enable feed: %w", err)
+		}
+	}
+
+	fmt.Printf("✅ Updated '%s'\n", feed.Name)
+	printFeedAuth(auth)
+	printFetchIntervals(minInterval, maxInterval)
+	if flags.enable {
+		fmt.Println("  Enabled: it will be fetched on the next agg cycle")
+	}
+	return nil
+}
+
 // HandlerUnfollow allows a user to unfollow a feed.
-func HandlerUnfollow(s *State, cmd Command, user database.User) error {
+func HandlerUnfollow(ctx context.Context, s *State, cmd Command, user database.User) error {
 	// Ensure feed URL is provided
 	if len(cmd.Args) < 1 {
 		return errors.New("usage: unfollow <feed_url>")
@@ -281,7 +549,7 @@ func HandlerUnfollow(s *State, cmd Command, user database.User) error {
 	feedURL := cmd.Args[0]
 
 	// Get the feed by URL
-	feed, err := s.DB.GetFeedByUrl(context.Background(), feedURL)
+	feed, err := s.DB.GetFeedByUrl(ctx, feedURL)
 	if err != nil {
 		return fmt.Errorf("no feed found with URL: %s", feedURL)
 	}
@@ -293,7 +561,7 @@ func HandlerUnfollow(s *State, cmd Command, user database.User) error {
 	}
 
 	// Delete feed follow record
-	err = s.DB.DeleteFeedFollow(context.Background(), params)
+	err = s.DB.DeleteFeedFollow(ctx, params)
 	if err != nil {
 		return fmt.Errorf("failed to unfollow feed: %w", err)
 	}
@@ -304,7 +572,7 @@ func HandlerUnfollow(s *State, cmd Command, user database.User) error {
 }
 
 // HandlerBrowse prints recent posts for a user.
-func HandlerBrowse(s *State, cmd Command, user database.User) error {
+func HandlerBrowse(ctx context.Context, s *State, cmd Command, user database.User) error {
 	// Default limit to 2 if not provided
 	limit := 2
 	if len(cmd.Args) > 0 {
@@ -316,7 +584,7 @@ func HandlerBrowse(s *State, cmd Command, user database.User) error {
 	}
 
 	// Fetch posts using the struct parameter
-	posts, err := s.DB.GetPostsForUser(context.Background(), database.GetPostsForUserParams{
+	posts, err := s.DB.GetPostsForUser(ctx, database.GetPostsForUserParams{
 		Name:  user.Name,
 		Limit: int32(limit),
 	})
@@ -327,7 +595,226 @@ func HandlerBrowse(s *State, cmd Command, user database.User) error {
 	// Print posts
 	fmt.Println("\n📌 Recent Posts:")
 	for _, post := range posts {
-		fmt.Printf("- %s\n  📅 %s\n  🔗 %s\n\n", post.Title, post.PublishedAt.Time.Format(time.RFC822), post.Url)
+		publishedAt := "unknown date"
+		if post.PublishedAt.Valid {
+			publishedAt = post.PublishedAt.Time.Format(time.RFC822)
+		}
+		if post.RevisedAt.Valid {
+			publishedAt += " (updated " + post.RevisedAt.Time.Format(time.RFC822) + ")"
+		}
+		fmt.Printf("- %s\n  📅 %s\n  🔗 %s\n  🆔 %s\n\n", post.Title, publishedAt, post.Url, post.ID)
+	}
+	return nil
+}
+
+// HandlerShow prints the full content of a post.
+func HandlerShow(ctx context.Context, s *State, cmd Command, user database.User) error {
+	if len(cmd.Args) < 1 {
+		return errors.New("usage: show <post_id>")
+	}
+	postID, err := uuid.Parse(cmd.Args[0])
+	if err != nil {
+		return fmt.Errorf("invalid post ID: %s", cmd.Args[0])
+	}
+
+	post, err := s.DB.GetPostForUser(ctx, database.GetPostForUserParams{
+		Name: user.Name,
+		ID:   postID,
+	})
+	if err != nil {
+		return fmt.Errorf("no post found with ID %s in your followed feeds", postID)
+	}
+
+	publishedAt := "unknown date"
+	if post.PublishedAt.Valid {
+		publishedAt = post.PublishedAt.Time.Format(time.RFC822)
+	}
+	if post.RevisedAt.Valid {
+		publishedAt += " (updated " + post.RevisedAt.Time.Format(time.RFC822) + ")"
+	}
+	fmt.Printf("\n%s\n", post.Title)
+	if post.Author.Valid {
+		fmt.Printf("✍️  %s\n", post.Author.String)
+	}
+	fmt.Printf("📅 %s\n🔗 %s\n\n", publishedAt, post.Url)
+
+	// Prefer the full content, falling back to the description
+	body := post.Content.String
+	if body == "" {
+		body = post.Description.String
+	}
+	if body == "" {
+		fmt.Println("(no content)")
+		return nil
+	}
+
+	// Resolve relative links against the item link, or the feed URL
+	baseURL := post.Url
+	if baseURL == "" {
+		baseURL = post.FeedUrl
+	}
+	fmt.Println(render.Text(body, baseURL, render.DefaultWidth))
+	return nil
+}
+
+// HandlerEpisodes prints recent podcast episodes from the user's followed feeds.
+func HandlerEpisodes(ctx context.Context, s *State, cmd Command, user database.User) error {
+	// Default limit to 10 if not provided
+	limit := 10
+	if len(cmd.Args) > 0 {
+		parsedLimit, err := strconv.Atoi(cmd.Args[0])
+		if err != nil || parsedLimit < 1 {
+			return errors.New("invalid limit; must be a positive integer")
+		}
+		limit = parsedLimit
+	}
+
+	episodes, err := s.DB.GetEpisodesForUser(ctx, database.GetEpisodesForUserParams{
+		Name:  user.Name,
+		Limit: int32(limit),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to fetch episodes: %w", err)
+	}
+
+	// Print episodes
+	fmt.Println("\n🎧 Recent Episodes:")
+	for _, episode := range episodes {
+		title := episode.Title
+		if episode.Episode.Valid {
+			title = fmt.Sprintf("#%d %s", episode.Episode.Int32, title)
+		}
+		publishedAt := "unknown date"
+		if episode.PublishedAt.Valid {
+			publishedAt = episode.PublishedAt.Time.Format(time.RFC822)
+		}
+		duration := "unknown length"
+		if episode.DurationSeconds.Valid {
+			duration = formatDuration(int(episode.DurationSeconds.Int32))
+		}
+
+		fmt.Printf("- %s (%s)\n  📅 %s  ⏱ %s\n  🔗 %s\n", title, episode.FeedName, publishedAt, duration, episode.Url)
+		if episode.MimeType.Valid {
+			fmt.Printf("  🎞 %s\n", episode.MimeType.String)
+		}
+		fmt.Println()
+	}
+	return nil
+}
+
+// formatDuration formats a number of seconds as H:MM:SS or M:SS.
+func formatDuration(seconds int) string {
+	hours, minutes, secs := seconds/3600, seconds%3600/60, seconds%60
+	if hours > 0 {
+		return fmt.Sprintf("%d:%02d:%02d", hours, minutes, secs)
+	}
+	return fmt.Sprintf("%d:%02d", minutes, secs)
+}
+
+// HandlerImportOPML imports feeds from an OPML file and follows them.
+func HandlerImportOPML(ctx context.Context, s *State, cmd Command, user database.User) error {
+	if len(cmd.Args) < 1 {
+		return errors.New("usage: import-opml <file>")
+	}
+
+	file, err := os.Open(cmd.Args[0])
+	if err != nil {
+		return fmt.Errorf("failed to open OPML file: %w", err)
+	}
+	defer file.Close()
+
+	feeds, err := opml.Parse(file)
+	if err != nil {
+		return err
+	}
+
+	// Import all or nothing, so a failure part way can simply be retried
+	created := 0
+	err = s.inTx(ctx, func(q *database.Queries) error {
+		for _, f := range feeds {
+			now := time.Now()
+
+			// Reuse the existing feed row, or create it
+			var feedID uuid.UUID
+			feed, err := q.GetFeedByUrl(ctx, f.URL)
+			switch {
+			case err == nil:
+				feedID = feed.ID
+			case errors.Is(err, sql.ErrNoRows):
+				name := f.Title
+				if name == "" {
+					name = f.URL
+				}
+				newFeed, err := q.CreateFeed(ctx, database.CreateFeedParams{
+					ID:        uuid.New(),
+					CreatedAt: now,
+					UpdatedAt: now,
+					Name:      name,
+					Url:       f.URL,
+					UserID:    user.ID,
+				})
+				if err != nil {
+					return fmt.Errorf("failed to create feed %s: %w", f.URL, err)
+				}
+				feedID = newFeed.ID
+				created++
+			default:
+				return fmt.Errorf("failed to look up feed %s: %w", f.URL, err)
+			}
+
+			// Follow the feed, keeping the OPML folder as its category
+			err = q.ImportFeedFollow(ctx, database.ImportFeedFollowParams{
+				ID:        uuid.New(),
+				CreatedAt: now,
+				UpdatedAt: now,
+				UserID:    user.ID,
+				FeedID:    feedID,
+				Category:  sql.NullString{String: f.Category, Valid: f.Category != ""},
+			})
+			if err != nil {
+				return fmt.Errorf("failed to follow feed %s: %w", f.URL, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("✅ Imported %d feeds (%d new) for %s\n", len(feeds), created, user.Name)
+	return nil
+}
+
+// HandlerExportOPML writes the user's followed feeds as OPML to a file or stdout.
+func HandlerExportOPML(ctx context.Context, s *State, cmd Command, user database.User) error {
+	follows, err := s.DB.GetFeedFollowsForUser(ctx, user.Name)
+	if err != nil {
+		return fmt.Errorf("failed to fetch followed feeds: %w", err)
+	}
+
+	feeds := make([]opml.Feed, 0, len(follows))
+	for _, follow := range follows {
+		feeds = append(feeds, opml.Feed{
+			Title:    follow.FeedName,
+			URL:      follow.FeedUrl,
+			Category: follow.Category.String,
+		})
+	}
+
+	// Write to stdout unless a file is given
+	if len(cmd.Args) < 1 {
+		return opml.Write(os.Stdout, fmt.Sprintf("%s's gator subscriptions", user.Name), feeds)
+	}
+
+	file, err := os.Create(cmd.Args[0])
+	if err != nil {
+		return fmt.Errorf("failed to create OPML file: %w", err)
+	}
+	defer file.Close()
+
+	if err := opml.Write(file, fmt.Sprintf("%s's gator subscriptions", user.Name), feeds); err != nil {
+		return err
 	}
+	fmt.Printf("✅ Exported %d feeds to %s\n", len(feeds), cmd.Args[0])
 	return nil
 }
@@ -0,0 +1,128 @@
+package cli
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmacneill66/go_projects/gator/internal/database"
+	"github.com/jmacneill66/go_projects/gator/internal/rss"
+)
+
+func TestHandlerImportOPML(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "feeds.opml")
+	err := os.WriteFile(path, []byte(`<opml version="2.0"><body>
+<outline text="A/B folder">
+	<outline text="One" xmlUrl="https://example.com/1.xml"/>
+	<outline text="Two" xmlUrl="https://example.com/2.xml"/>
+</outline>
+</body></opml>`), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := database.User{ID: uuid.New(), Name: "ada"}
+
+	for _, failing := range []string{"", "https://example.com/2.xml"} {
+		s, db := newTestState(t, rss.NewFixtureFetcher(nil))
+		var categories []driver.Value
+		db.handle("CreateFeed", func(args []driver.Value) ([]string, [][]driver.Value, error) {
+			if args[4] == failing {
+				return nil, nil, errors.New("connection reset")
+			}
+			now := time.Now()
+			return fakeRow([]string{"id", "created_at", "updated_at", "name", "url", "user_id"},
+				args[0], now, now, args[3], args[4], args[5])
+		})
+		db.handle("ImportFeedFollow", func(args []driver.Value) ([]string, [][]driver.Value, error) {
+			categories = append(categories, args[5])
+			return nil, nil, nil
+		})
+
+		err := HandlerImportOPML(context.Background(), s, Command{Name: "import-opml", Args: []string{path}}, user)
+		if failing == "" {
+			if err != nil {
+				t.Fatal(err)
+			}
+			if db.called("COMMIT") != 1 || len(categories) != 2 {
+				t.Errorf("got %d commits and %d follows, want 1 and 2", db.called("COMMIT"), len(categories))
+			}
+			for _, category := range categories {
+				if category != `A\/B folder` {
+					t.Errorf("got category %v, want the escaped folder name", category)
+				}
+			}
+			continue
+		}
+
+		// A failure part way rolls back the feeds imported before it
+		if err == nil {
+			t.Fatal("got no error when creating a feed failed")
+		}
+		if db.called("COMMIT") != 0 || db.called("ROLLBACK") != 1 {
+			t.Errorf("got %d commits and %d rollbacks, want 0 and 1", db.called("COMMIT"), db.called("ROLLBACK"))
+		}
+	}
+}
+
+func TestHandlerAddFeedKnownURL(t *testing.T) {
+	const (
+		currentURL = "https://example.com/feed.xml"
+		aliasURL   = "https://old.example.com/rss"
+		newURL     = "https://new.example.com/feed.xml"
+	)
+	existingID := uuid.New()
+	user := database.User{ID: uuid.New(), Name: "ada"}
+
+	tests := []struct {
+		url        string
+		wantCreate bool
+	}{
+		{currentURL, false},
+		{aliasURL, false},
+		{newURL, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			s, db := newTestState(t, rss.NewFixtureFetcher(map[string]rss.Fixture{
+				currentURL: rssFixture(),
+				aliasURL:   rssFixture(),
+				newURL:     rssFixture(),
+			}))
+			// GetFeedByUrl also matches the URLs a feed has moved from
+			db.handle("GetFeedByUrl", func(args []driver.Value) ([]string, [][]driver.Value, error) {
+				if url := args[0].(string); url != currentURL && url != aliasURL {
+					return nil, nil, nil
+				}
+				return fakeRow([]string{"id", "name"}, existingID.String(), "Example")
+			})
+			db.handle("CreateFeed", func(args []driver.Value) ([]string, [][]driver.Value, error) {
+				now := time.Now()
+				return fakeRow([]string{"id", "created_at", "updated_at", "name", "url", "user_id"},
+					args[0], now, now, args[3], args[4], args[5])
+			})
+			var followed driver.Value
+			db.handle("CreateFeedFollow", func(args []driver.Value) ([]string, [][]driver.Value, error) {
+				followed = args[4]
+				now := time.Now()
+				return fakeRow([]string{"id", "created_at", "updated_at", "user_name", "feed_name"},
+					args[0], now, now, user.Name, "Example")
+			})
+
+			err := HandlerAddFeed(context.Background(), s, Command{Name: "addfeed", Args: []string{"Example", tt.url}}, user)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if created := db.called("CreateFeed") > 0; created != tt.wantCreate {
+				t.Errorf("created a feed: %v, want %v", created, tt.wantCreate)
+			}
+			if !tt.wantCreate && followed != existingID.String() {
+				t.Errorf("followed feed %v, want the existing feed %s", followed, existingID)
+			}
+		})
+	}
+}
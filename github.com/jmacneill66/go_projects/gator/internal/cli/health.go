@@ -0,0 +1,133 @@
+package cli
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jmacneill66/go_projects/gator/internal/database"
+	"github.com/jmacneill66/go_projects/gator/internal/rss"
+)
+
+// defaultDisableAfterFailures is the number of failed fetches in a row after
+// which a feed is disabled, unless the config sets another. With the backoff
+// below, that is several days of failures.
+const defaultDisableAfterFailures = 10
+
+// disableAfterFailures returns the configured auto-disable threshold, or 0
+// when feeds are never disabled.
+func disableAfterFailures(s *State) int32 {
+	switch n := s.Cfg.Fetch.DisableAfterFailures; {
+	case n < 0:
+		return 0
+	case n == 0:
+		return defaultDisableAfterFailures
+	default:
+		return int32(n)
+	}
+}
+
+// failureBackoff returns how long to wait before retrying a feed after
+// failures fetches in a row have failed: its minimum interval, doubled for
+// each further failure, up to its maximum interval.
+func failureBackoff(minInterval, maxInterval time.Duration, failures int32) time.Duration {
+	backoff := minInterval
+	for range failures - 1 {
+		backoff *= 2
+		if backoff >= maxInterval {
+			return max(maxInterval, minInterval)
+		}
+	}
+	return backoff
+}
+
+// statusCode returns the HTTP status of a fetch, or NULL when there was none.
+func statusCode(code int) sql.NullInt32 {
+	return sql.NullInt32{Int32: int32(code), Valid: code != 0}
+}
+
+// errorStatusCode returns the HTTP status of a failed fetch, or NULL when
+// it failed before the server answered.
+func errorStatusCode(err error) sql.NullInt32 {
+	var statusErr *rss.StatusError
+	if errors.As(err, &statusErr) {
+		return statusCode(statusErr.StatusCode)
+	}
+	return sql.NullInt32{}
+}
+
+// recordFeedFailure stores the error of a failed fetch and backs the feed
+// off, disabling it after too many failures in a row.
+func recordFeedFailure(ctx context.Context, s *State, feed database.ClaimFeedsToFetchRow, fetchErr error) {
+	health, err := s.DB.RecordFeedFailure(ctx, database.RecordFeedFailureParams{
+		LastError:      sql.NullString{String: fetchErr.Error(), Valid: true},
+		LastStatusCode: errorStatusCode(fetchErr),
+		DisableAfter:   disableAfterFailures(s),
+		ID:             feed.ID,
+	})
+	if err != nil {
+		log.Printf("Error recording fetch failure of %s: %v\n", feed.Url, err)
+		return
+	}
+	if health.DisabledAt.Valid {
+		fmt.Printf("🚫 Disabled %s after %d failed fetches in a row; fix it and run editfeed %s --enable\n",
+			feed.Name, health.ConsecutiveFailures, feed.Url)
+		return
+	}
+
+	minInterval, maxInterval := fetchIntervalBounds(feed.MinFetchIntervalSeconds, feed.MaxFetchIntervalSeconds)
+	backoff := failureBackoff(minInterval, maxInterval, health.ConsecutiveFailures)
+	err = s.DB.ScheduleNextFetch(ctx, database.ScheduleNextFetchParams{
+		IntervalSeconds: int32(backoff.Seconds()),
+		ID:              feed.ID,
+	})
+	if err != nil {
+		log.Printf("Error scheduling retry of %s: %v\n", feed.Url, err)
+		return
+	}
+	fmt.Printf("⏳ Retrying %s in %s (%d failed fetches in a row)\n", feed.Name, backoff, health.ConsecutiveFailures)
+}
+
+// printBrokenFeeds prints the feeds whose recent fetches failed, and those
+// that were disabled or are gone, for their owners to fix or remove.
+func printBrokenFeeds(ctx context.Context, s *State) error {
+	feeds, err := s.DB.GetBrokenFeeds(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to fetch broken feeds: %w", err)
+	}
+	if len(feeds) == 0 {
+		fmt.Println("✅ No broken feeds.")
+		return nil
+	}
+
+	fmt.Println("\n=== Broken Feeds ===")
+	for _, feed := range feeds {
+		fmt.Printf("- %s\n  URL: %s\n", feed.Name, feed.Url)
+		switch {
+		case feed.DeadAt.Valid:
+			fmt.Printf("  Dead since: %s (410 Gone)\n", feed.DeadAt.Time.Format(time.RFC822))
+		case feed.DisabledAt.Valid:
+			fmt.Printf("  Disabled since: %s, after %d failed fetches in a row\n",
+				feed.DisabledAt.Time.Format(time.RFC822), feed.ConsecutiveFailures)
+		default:
+			fmt.Printf("  Failing: %d fetches in a row\n", feed.ConsecutiveFailures)
+			if feed.NextFetchAt.Valid {
+				fmt.Printf("  Next retry: %s\n", feed.NextFetchAt.Time.Format(time.RFC822))
+			}
+		}
+		printField("Last error", feed.LastError)
+		if feed.LastStatusCode.Valid {
+			fmt.Printf("  Last status: %d\n", feed.LastStatusCode.Int32)
+		}
+		if feed.LastSuccessAt.Valid {
+			fmt.Printf("  Last success: %s\n", feed.LastSuccessAt.Time.Format(time.RFC822))
+		} else {
+			fmt.Println("  Last success: never")
+		}
+		fmt.Printf("  Added by: %s\n\n", feed.UserName)
+	}
+	return nil
+}
@@ -0,0 +1,31 @@
+package cli
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFailureBackoff(t *testing.T) {
+	tests := []struct {
+		name     string
+		min, max time.Duration
+		failures int32
+		want     time.Duration
+	}{
+		{"first failure", 15 * time.Minute, 24 * time.Hour, 1, 15 * time.Minute},
+		{"second failure", 15 * time.Minute, 24 * time.Hour, 2, 30 * time.Minute},
+		{"fifth failure", 15 * time.Minute, 24 * time.Hour, 5, 4 * time.Hour},
+		{"capped at the maximum", 15 * time.Minute, time.Hour, 5, time.Hour},
+		{"reaches the maximum exactly", 15 * time.Minute, time.Hour, 3, time.Hour},
+		{"many failures", 15 * time.Minute, 24 * time.Hour, 1000, 24 * time.Hour},
+		{"no failures", 15 * time.Minute, 24 * time.Hour, 0, 15 * time.Minute},
+		{"minimum above maximum", 2 * time.Hour, time.Hour, 3, 2 * time.Hour},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := failureBackoff(tt.min, tt.max, tt.failures); got != tt.want {
+				t.Errorf("failureBackoff(%s, %s, %d) = %s, want %s", tt.min, tt.max, tt.failures, got, tt.want)
+			}
+		})
+	}
+}
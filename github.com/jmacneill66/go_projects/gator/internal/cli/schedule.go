@@ -0,0 +1,145 @@
+package cli
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/jmacneill66/go_projects/gator/internal/database"
+	"github.com/jmacneill66/go_projects/gator/internal/rss"
+)
+
+// Fetch interval settings, used when a feed has no bounds of its own
+const (
+	defaultMinFetchInterval = 15 * time.Minute
+	defaultMaxFetchInterval = 24 * time.Hour
+
+	// defaultFetchInterval is used until a feed has enough dated posts to
+	// estimate how often it posts
+	defaultFetchInterval = time.Hour
+)
+
+// fetchSchedule holds what is known about how often a feed changes.
+type fetchSchedule struct {
+	// Bounds on the interval, from the feed's settings or the defaults
+	min, max time.Duration
+
+	// hint is the longest interval the publisher asked for, through <ttl>,
+	// sy:updatePeriod or Cache-Control
+	hint time.Duration
+
+	// Publishing times of the feed's most recent posts
+	postCount int64
+	span      time.Duration
+	sinceLast time.Duration
+}
+
+// interval returns how long to wait before fetching the feed again.
+func (f fetchSchedule) interval() time.Duration {
+	interval := defaultFetchInterval
+	if f.postCount >= 2 {
+		// Poll twice per usual gap between posts, so a new post waits half
+		// a gap at most. A feed that has been quiet for longer than its
+		// usual gap is polled less and less often.
+		gap := f.span / time.Duration(f.postCount-1)
+		interval = max(gap, f.sinceLast) / 2
+	}
+
+	// Publishers' hints are a floor, but the feed's own bounds come first
+	interval = max(interval, f.hint)
+	return min(max(interval, f.min), max(f.max, f.min))
+}
+
+// fetchIntervalBounds returns a feed's interval bounds, falling back to the
+// defaults for bounds it does not set.
+func fetchIntervalBounds(minSeconds, maxSeconds sql.NullInt32) (time.Duration, time.Duration) {
+	minInterval, maxInterval := defaultMinFetchInterval, defaultMaxFetchInterval
+	if minSeconds.Valid {
+		minInterval = time.Duration(minSeconds.Int32) * time.Second
+	}
+	if maxSeconds.Valid {
+		maxInterval = time.Duration(maxSeconds.Int32) * time.Second
+	}
+	return minInterval, maxInterval
+}
+
+// scheduleNextFetch sets when a successfully fetched feed is next due, from
+// its posting frequency and the publisher's hints. A 304 response has no
+// body, so only its Cache-Control header is used.
+func scheduleNextFetch(ctx context.Context, q *database.Queries, feed database.ClaimFeedsToFetchRow, result *rss.FetchResult) error {
+	stats, err := q.GetFeedPostingStats(ctx, feed.ID)
+	if err != nil {
+		return fmt.Errorf("failed to read posting frequency: %w", err)
+	}
+
+	schedule := fetchSchedule{
+		hint:      result.MaxAge,
+		postCount: stats.PostCount,
+		span:      time.Duration(stats.SpanSeconds * float64(time.Second)),
+		sinceLast: time.Duration(max(stats.SinceLastSeconds, 0) * float64(time.Second)),
+	}
+	schedule.min, schedule.max = fetchIntervalBounds(feed.MinFetchIntervalSeconds, feed.MaxFetchIntervalSeconds)
+	if result.Feed != nil {
+		schedule.hint = max(schedule.hint, result.Feed.UpdateInterval())
+	}
+
+	interval := schedule.interval().Round(time.Second)
+	err = q.ScheduleNextFetch(ctx, database.ScheduleNextFetchParams{
+		IntervalSeconds: int32(interval.Seconds()),
+		ID:              feed.ID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to schedule next fetch: %w", err)
+	}
+	fmt.Printf("⏰ Next fetch of %s in %s\n", feed.Name, interval)
+	return nil
+}
+
+// intervalFlag parses an interval bound given as a flag: a duration, or
+// "default" to clear the feed's own bound.
+func intervalFlag(name, value string) (sql.NullInt32, error) {
+	if value == "default" {
+		return sql.NullInt32{}, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d < time.Minute || d.Seconds() > float64(1<<31-1) {
+		return sql.NullInt32{}, fmt.Errorf("invalid --%s %q; use a duration of at least 1m, or default", name, value)
+	}
+	return sql.NullInt32{Int32: int32(d.Seconds()), Valid: true}, nil
+}
+
+// intervalsSet reports whether the interval flags were given.
+func (f *feedFlags) intervalsSet() bool {
+	return f.set["min-interval"] || f.set["max-interval"]
+}
+
+// applyIntervals changes the interval bounds given by the flags and checks
+// that the minimum does not exceed the maximum.
+func (f *feedFlags) applyIntervals(minSeconds, maxSeconds *sql.NullInt32) error {
+	var err error
+	if f.set["min-interval"] {
+		if *minSeconds, err = intervalFlag("min-interval", f.minInterval); err != nil {
+			return err
+		}
+	}
+	if f.set["max-interval"] {
+		if *maxSeconds, err = intervalFlag("max-interval", f.maxInterval); err != nil {
+			return err
+		}
+	}
+	minInterval, maxInterval := fetchIntervalBounds(*minSeconds, *maxSeconds)
+	if minInterval > maxInterval {
+		return fmt.Errorf("minimum interval %s is longer than maximum interval %s", minInterval, maxInterval)
+	}
+	return nil
+}
+
+// printFetchIntervals prints a feed's interval bounds when it has its own.
+func printFetchIntervals(minSeconds, maxSeconds sql.NullInt32) {
+	if !minSeconds.Valid && !maxSeconds.Valid {
+		return
+	}
+	minInterval, maxInterval := fetchIntervalBounds(minSeconds, maxSeconds)
+	fmt.Printf("  Fetch interval: %s to %s\n", minInterval, maxInterval)
+}
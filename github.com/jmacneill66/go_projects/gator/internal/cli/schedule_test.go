@@ -0,0 +1,62 @@
+package cli
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFetchScheduleInterval(t *testing.T) {
+	const (
+		day     = 24 * time.Hour
+		minimum = defaultMinFetchInterval
+		maximum = defaultMaxFetchInterval
+	)
+	tests := []struct {
+		name     string
+		schedule fetchSchedule
+		want     time.Duration
+	}{
+		{"no posts", fetchSchedule{min: minimum, max: maximum}, defaultFetchInterval},
+		{"one post", fetchSchedule{min: minimum, max: maximum, postCount: 1, sinceLast: time.Minute}, defaultFetchInterval},
+		{
+			"daily posts",
+			fetchSchedule{min: minimum, max: maximum, postCount: 11, span: 10 * day, sinceLast: 2 * time.Hour},
+			12 * time.Hour,
+		},
+		{
+			"quiet for longer than the usual gap",
+			fetchSchedule{min: minimum, max: maximum, postCount: 5, span: 4 * time.Hour, sinceLast: 10 * time.Hour},
+			5 * time.Hour,
+		},
+		{
+			"frequent posts are held to the minimum",
+			fetchSchedule{min: minimum, max: maximum, postCount: 11, span: 100 * time.Minute, sinceLast: 5 * time.Minute},
+			minimum,
+		},
+		{
+			"quiet feeds are held to the maximum",
+			fetchSchedule{min: minimum, max: maximum, postCount: 11, span: 10 * day, sinceLast: 30 * day},
+			maximum,
+		},
+		{"publisher hint", fetchSchedule{min: minimum, max: maximum, hint: 3 * time.Hour}, 3 * time.Hour},
+		{
+			"hint below the posting interval",
+			fetchSchedule{min: minimum, max: maximum, hint: time.Hour, postCount: 11, span: 10 * day},
+			12 * time.Hour,
+		},
+		{"hint above the maximum", fetchSchedule{min: minimum, max: maximum, hint: 2 * day}, maximum},
+		{
+			"feed's own minimum",
+			fetchSchedule{min: 30 * time.Minute, max: maximum, postCount: 11, span: 100 * time.Minute},
+			30 * time.Minute,
+		},
+		{"minimum above maximum", fetchSchedule{min: 2 * time.Hour, max: time.Hour}, 2 * time.Hour},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.schedule.interval(); got != tt.want {
+				t.Errorf("interval() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
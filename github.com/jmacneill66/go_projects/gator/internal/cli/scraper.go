@@ -2,61 +2,299 @@ package cli
 
 import (
 	"context"
+	"crypto/sha256"
 	"database/sql"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"github.com/jmacneill66/go_projects/gator/internal/database"
 	"github.com/jmacneill66/go_projects/gator/internal/rss"
 	"log"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
 )
 
-// ScrapeFeeds fetches RSS feeds and prints post titles.
-func ScrapeFeeds(s *State) {
-	ctx := context.Background()
+// ScrapeSummary counts the feeds and posts handled by ScrapeFeeds.
+type ScrapeSummary struct {
+	Feeds        int // feeds fetched and saved
+	Failed       int // feeds that failed to fetch or save
+	Interrupted  int // feeds skipped or rolled back because of a shutdown
+	NewPosts     int
+	UpdatedPosts int
+}
+
+// Add adds the counts of another summary to s.
+func (s *ScrapeSummary) Add(other ScrapeSummary) {
+	s.Feeds += other.Feeds
+	s.Failed += other.Failed
+	s.Interrupted += other.Interrupted
+	s.NewPosts += other.NewPosts
+	s.UpdatedPosts += other.UpdatedPosts
+}
+
+// ScrapeFeeds claims up to batchSize due feeds and fetches them with at
+// most concurrency feeds in flight. A feed that fails does not stop the
+// others; the errors of all failed feeds are returned. Once ctx is
+// cancelled no more feeds are started, feeds in flight are rolled back, and
+// the claims of feeds that were not started are released.
+func ScrapeFeeds(ctx context.Context, s *State, concurrency, batchSize int) (ScrapeSummary, []error) {
+	var summary ScrapeSummary
 
-	// Get the next feed to fetch
-	feed, err := s.DB.GetNextFeedToFetch(ctx)
+	// Claim the feeds to fetch, marking them as fetched
+	feeds, err := s.DB.ClaimFeedsToFetch(ctx, database.ClaimFeedsToFetchParams{
+		DefaultMinSeconds: int32(defaultMinFetchInterval.Seconds()),
+		BatchSize:         int32(batchSize),
+	})
 	if err != nil {
+		if ctx.Err() != nil {
+			return summary, nil
+		}
+		return summary, []error{fmt.Errorf("failed to claim feeds: %w", err)}
+	}
+	if len(feeds) == 0 {
 		log.Println("No feeds available to fetch.")
-		return
+		return summary, nil
+	}
+
+	var (
+		mu        sync.Mutex
+		errs      []error
+		unstarted []database.ClaimFeedsToFetchRow
+		wg        sync.WaitGroup
+	)
+	jobs := make(chan database.ClaimFeedsToFetchRow)
+	for range min(concurrency, len(feeds)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for feed := range jobs {
+				// A feed handed out as the shutdown began is not started
+				if ctx.Err() != nil {
+					mu.Lock()
+					unstarted = append(unstarted, feed)
+					mu.Unlock()
+					continue
+				}
+
+				startedAt := time.Now()
+				stats, err := scrapeFeed(ctx, s, feed)
+				logFeedFetch(ctx, s, feed.ID, startedAt, stats, err)
+				interrupted := err != nil && ctx.Err() != nil
+				if err != nil && !interrupted {
+					recordFeedFailure(ctx, s, feed, err)
+				}
+
+				mu.Lock()
+				switch {
+				case interrupted:
+					summary.Interrupted++
+				case err != nil:
+					summary.Failed++
+					errs = append(errs, fmt.Errorf("%s: %w", feed.Url, err))
+				default:
+					summary.Feeds++
+					summary.NewPosts += stats.newPosts
+					summary.UpdatedPosts += stats.updatedPosts
+				}
+				mu.Unlock()
+			}
+		}()
 	}
 
+	// Stop handing out feeds once a shutdown is requested
+	dispatched := 0
+dispatch:
+	for _, feed := range feeds {
+		select {
+		case jobs <- feed:
+			dispatched++
+		case <-ctx.Done():
+			break dispatch
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	unstarted = append(unstarted, feeds[dispatched:]...)
+	releaseFeedClaims(ctx, s, unstarted)
+	summary.Interrupted += len(unstarted)
+
+	fmt.Printf("\n✅ Fetched %d feeds, %d failed", summary.Feeds, summary.Failed)
+	if summary.Interrupted > 0 {
+		fmt.Printf(", %d interrupted", summary.Interrupted)
+	}
+	fmt.Printf(" (%d new posts, %d updated)\n", summary.NewPosts, summary.UpdatedPosts)
+	return summary, errs
+}
+
+// releaseFeedClaims restores the schedule of claimed feeds that were never
+// fetched, so that a shutdown does not put them off by their minimum
+// interval.
+func releaseFeedClaims(ctx context.Context, s *State, feeds []database.ClaimFeedsToFetchRow) {
+	// ctx has been cancelled by the shutdown
+	ctx = context.WithoutCancel(ctx)
+	for _, feed := range feeds {
+		err := s.DB.ReleaseFeedClaim(ctx, database.ReleaseFeedClaimParams{
+			LastFetchedAt: feed.ClaimedLastFetchedAt,
+			NextFetchAt:   feed.ClaimedNextFetchAt,
+			ID:            feed.ID,
+		})
+		if err != nil {
+			log.Printf("Error releasing claim of feed %s: %v\n", feed.Url, err)
+		}
+	}
+}
+
+// fetchStats describes one fetch of a feed, for the summary and fetch log.
+type fetchStats struct {
+	statusCode   int
+	bytes        int64
+	items        int
+	newPosts     int
+	updatedPosts int
+}
+
+// scrapeFeed fetches a claimed feed and saves its posts.
+func scrapeFeed(ctx context.Context, s *State, feed database.ClaimFeedsToFetchRow) (fetchStats, error) {
 	fmt.Printf("\n🔄 Fetching feed: %s (%s)\n", feed.Name, feed.Url)
 
-	// Mark feed as fetched
-	err = s.DB.MarkFeedFetched(ctx, feed.ID)
+	// Read the feed's credentials and extra headers
+	var stats fetchStats
+	auth, err := loadFeedAuth(feed.AuthType, feed.AuthUsername, feed.AuthSecretEnv, feed.Headers)
 	if err != nil {
-		log.Printf("Error marking feed as fetched: %v\n", err)
-		return
+		return stats, err
+	}
+	headers, err := auth.requestHeaders()
+	if err != nil {
+		return stats, err
 	}
 
-	// Fetch and parse the feed
-	rssFeed, err := rss.FetchFeed(ctx, feed.Url)
+	// Fetch and parse the feed, sending the validators from the last fetch
+	result, err := s.Fetcher.FetchFeed(ctx, feed.Url, rss.FetchOptions{
+		ETag:         feed.Etag.String,
+		LastModified: feed.LastModified.String,
+		Headers:      headers,
+	})
+	if errors.Is(err, rss.ErrGone) {
+		if err := s.DB.MarkFeedDead(ctx, feed.ID); err != nil {
+			log.Printf("Error marking feed as dead: %v\n", err)
+		}
+		return stats, fmt.Errorf("%w; it will no longer be fetched", err)
+	}
+	if result != nil {
+		stats.statusCode = result.StatusCode
+		stats.bytes = result.Bytes
+	}
 	if err != nil {
-		log.Printf("Error fetching RSS feed: %v\n", err)
-		return
+		return stats, err
+	}
+	if result.Feed != nil {
+		stats.items = len(result.Feed.Channel.Item)
 	}
-	/*
-		// Print post titles
-		fmt.Println("\n📢 Latest Posts:")
-		for _, item := range rssFeed.Channel.Item {
-			fmt.Printf("- %s\n", item.Title)
-		} */
 
-	// Save posts
-	for _, item := range rssFeed.Channel.Item {
-		// Parse published_at
-		publishedAt, err := time.Parse(time.RFC1123, item.PubDate)
+	// Save the validators and schedule together with the posts, so that an
+	// interrupted save is fetched again in full rather than answered with 304
+	err = s.inTx(ctx, func(q *database.Queries) error {
+		// Move the feed once it has been permanently redirected often enough
+		if err := trackFeedMove(ctx, q, feed.ID, feed.Url, result); err != nil {
+			return err
+		}
+
+		if result.ETag != feed.Etag.String || result.LastModified != feed.LastModified.String {
+			err := q.UpdateFeedValidators(ctx, database.UpdateFeedValidatorsParams{
+				ID:           feed.ID,
+				Etag:         sql.NullString{String: result.ETag, Valid: result.ETag != ""},
+				LastModified: sql.NullString{String: result.LastModified, Valid: result.LastModified != ""},
+			})
+			if err != nil {
+				return fmt.Errorf("failed to save cache validators: %w", err)
+			}
+		}
+
+		// Nothing to parse or save when the feed has not changed
+		if !result.NotModified {
+			if err := saveFeedMetadata(ctx, q, feed.ID, result.Feed); err != nil {
+				return err
+			}
+			created, updated, err := savePosts(ctx, q, feed.ID, result.Feed)
+			if err != nil {
+				return err
+			}
+			stats.newPosts, stats.updatedPosts = created, updated
+
+			// Posts keyed by their URL that the full feed did not list have
+			// rolled off it, so they will not be adopted later either
+			if err := q.MarkLegacyPostsAdopted(ctx, feed.ID); err != nil {
+				return fmt.Errorf("failed to record adoption of posts without guids: %w", err)
+			}
+		}
+		err := q.RecordFeedSuccess(ctx, database.RecordFeedSuccessParams{
+			ID:             feed.ID,
+			LastStatusCode: statusCode(result.StatusCode),
+		})
 		if err != nil {
-			log.Printf("Error parsing publish date for %s: %v\n", item.Title, err)
-			publishedAt = time.Now() // Default to now if parsing fails
+			return fmt.Errorf("failed to record fetch: %w", err)
+		}
+		return scheduleNextFetch(ctx, q, feed, result)
+	})
+	if err != nil {
+		// Nothing was saved
+		stats.newPosts, stats.updatedPosts = 0, 0
+		return stats, err
+	}
+	if result.NotModified {
+		fmt.Printf("✅ Feed not modified since last fetch: %s\n", feed.Name)
+		return stats, nil
+	}
+
+	// Ask the feed's hub, if any, to push future updates
+	subscribeWebSub(ctx, s, feed.ID, feed.Url, result.Feed)
+	return stats, nil
+}
+
+// savePosts saves new items as posts and updates edited ones, returning
+// how many were created and updated. It is shared by polling and WebSub
+// pushes, and expects q to be bound to a transaction: it stops at the first
+// database error, as the transaction cannot continue after one. When a feed
+// lists the same item twice, only the first is saved, so the copies are
+// not taken for edits of each other on every fetch.
+func savePosts(ctx context.Context, q *database.Queries, feedID uuid.UUID, rssFeed *rss.RSSFeed) (created, updated int, err error) {
+	// Posts saved before guids were stored are keyed by their URL, until
+	// the feed lists them again
+	legacy, err := q.FeedHasLegacyPosts(ctx, feedID)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to look up posts without guids: %w", err)
+	}
+
+	seen := make(map[string]bool, len(rssFeed.Channel.Item))
+	for _, item := range rssFeed.Channel.Item {
+		guid := item.Key()
+		if seen[guid] {
+			continue
 		}
+		seen[guid] = true
 
-		// Create post
-		postID := uuid.New()
-		now := time.Now()
+		// Parse published_at, leaving it unknown (NULL) if parsing fails
+		publishedAt, ok := rss.ParseDate(item.PubDate)
+		if !ok {
+			log.Printf("Unknown publish date for %s: %q\n", item.Title, item.PubDate)
+		}
+
+		// Give posts keyed by their URL the item's real key so they are
+		// not saved twice
+		if legacy {
+			err := q.AdoptLegacyPost(ctx, database.AdoptLegacyPostParams{
+				Guid:       guid,
+				FeedID:     feedID,
+				LegacyGuid: "legacy:" + item.Link,
+			})
+			if err != nil {
+				return created, updated, fmt.Errorf("failed to update key of post '%s': %w", item.Title, err)
+			}
+		}
 
 		var description sql.NullString
 		if item.Description != "" {
@@ -64,20 +302,226 @@ func ScrapeFeeds(s *State) {
 		} else {
 			description = sql.NullString{Valid: false}
 		}
+		content := sql.NullString{String: item.Content, Valid: item.Content != ""}
+		hash := contentHash(item.Title, item.Description, item.Content)
 
-		err = s.DB.CreatePost(ctx, database.CreatePostParams{
-			ID:          postID,
+		// Update posts the publisher has edited since we saved them, and
+		// add enclosures attached after the post was first saved
+		existing, err := q.GetPostByGuid(ctx, database.GetPostByGuidParams{
+			FeedID: feedID,
+			Guid:   guid,
+		})
+		if err == nil {
+			switch {
+			case existing.ContentHash == hash:
+			case !existing.HasContent && existing.ContentHash == contentHash(item.Title, item.Description, ""):
+				// Posts saved before full content was captured were hashed
+				// without it; their content is new to us, not an edit
+				err := q.UpdatePostContent(ctx, database.UpdatePostContentParams{
+					ID:          existing.ID,
+					Content:     content,
+					ContentHash: hash,
+				})
+				if err != nil {
+					return created, updated, fmt.Errorf("failed to save content of post '%s': %w", item.Title, err)
+				}
+			default:
+				if err := revisePost(ctx, q, existing.ID, item, description, content, hash); err != nil {
+					return created, updated, err
+				}
+				updated++
+			}
+			if err := saveEnclosures(ctx, q, existing.ID, item); err != nil {
+				return created, updated, err
+			}
+			continue
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return created, updated, fmt.Errorf("failed to look up post '%s': %w", item.Title, err)
+		}
+
+		// Create post
+		now := time.Now()
+		postID, err := q.CreatePost(ctx, database.CreatePostParams{
+			ID:          uuid.New(),
 			CreatedAt:   now,
 			UpdatedAt:   now,
 			Title:       item.Title,
 			Url:         item.Link,
 			Description: description,
-			PublishedAt: sql.NullTime{Time: publishedAt, Valid: true},
-			FeedID:      feed.ID,
+			PublishedAt: sql.NullTime{Time: publishedAt, Valid: ok},
+			FeedID:      feedID,
+			Content:     content,
+			Guid:        guid,
+			ContentHash: hash,
+			Author:      nullString(item.Creator),
 		})
 
+		if errors.Is(err, sql.ErrNoRows) {
+			continue // Post already saved
+		}
+		if err != nil {
+			return created, updated, fmt.Errorf("failed to insert post '%s': %w", item.Title, err)
+		}
+
+		if err := saveEnclosures(ctx, q, postID, item); err != nil {
+			return created, updated, err
+		}
+		created++
+	}
+	return created, updated, nil
+}
+
+// feedMoveThreshold is the number of consecutive fetches that must be
+// permanently redirected to the same URL before the feed's URL is rewritten.
+// A single 301 is not trusted, as misconfigured servers send them briefly.
+const feedMoveThreshold = 3
+
+// trackFeedMove records permanent redirects of a feed, and rewrites its URL
+// after feedMoveThreshold fetches in a row were redirected to the same place.
+// A feed is not moved onto the URL of another feed; its redirects are not
+// counted while another feed has the target URL.
+func trackFeedMove(ctx context.Context, q *database.Queries, feedID uuid.UUID, feedURL string, result *rss.FetchResult) error {
+	target := result.PermanentURL()
+	if target != "" && target != feedURL {
+		other, err := q.GetFeedByUrl(ctx, target)
+		switch {
+		case err == nil && other.ID != feedID:
+			fmt.Printf("↪️  %s redirects to %s, which is already the feed %s; not moving it\n", feedURL, target, other.Name)
+			target = ""
+		case err != nil && !errors.Is(err, sql.ErrNoRows):
+			return fmt.Errorf("failed to look up redirect target: %w", err)
+		}
+	}
+	if target == "" || target == feedURL {
+		if err := q.ClearFeedRedirect(ctx, feedID); err != nil {
+			return fmt.Errorf("failed to clear feed redirect: %w", err)
+		}
+		return nil
+	}
+
+	count, err := q.RecordFeedRedirect(ctx, database.RecordFeedRedirectParams{
+		ID:      feedID,
+		MovedTo: sql.NullString{String: target, Valid: true},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record feed redirect: %w", err)
+	}
+	if count < feedMoveThreshold {
+		return nil
+	}
+
+	err = q.MoveFeed(ctx, database.MoveFeedParams{
+		AliasID: uuid.New(),
+		ID:      feedID,
+		Url:     target,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to move feed to %s: %w", target, err)
+	}
+	fmt.Printf("🚚 Feed moved permanently: %s -> %s\n", feedURL, target)
+	return nil
+}
+
+// saveFeedMetadata stores the channel's title, description, site link,
+// language, image and generator on the feed.
+func saveFeedMetadata(ctx context.Context, q *database.Queries, feedID uuid.UUID, rssFeed *rss.RSSFeed) error {
+	channel := rssFeed.Channel
+	err := q.UpdateFeedMetadata(ctx, database.UpdateFeedMetadataParams{
+		ID:          feedID,
+		Title:       nullString(channel.Title),
+		Description: nullString(channel.Description),
+		SiteUrl:     nullString(channel.Link),
+		Language:    nullString(channel.Language),
+		ImageUrl:    nullString(channel.ImageURL),
+		Generator:   nullString(channel.Generator),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save feed metadata: %w", err)
+	}
+	return nil
+}
+
+// nullString returns a trimmed string, or NULL when it is empty.
+func nullString(value string) sql.NullString {
+	value = strings.TrimSpace(value)
+	return sql.NullString{String: value, Valid: value != ""}
+}
+
+// contentHash fingerprints the parts of a post a publisher might edit.
+// It must match the backfill in the post_revisions migration.
+func contentHash(title, description, content string) string {
+	sum := sha256.Sum256([]byte(title + "\n" + description + "\n" + content))
+	return hex.EncodeToString(sum[:])
+}
+
+// revisePost keeps the current version of an edited post as a revision
+// and replaces it with the new one.
+func revisePost(ctx context.Context, q *database.Queries, postID uuid.UUID, item rss.RSSItem, description, content sql.NullString, hash string) error {
+	err := q.CreatePostRevision(ctx, database.CreatePostRevisionParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now(),
+		PostID:    postID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save revision of post '%s': %w", item.Title, err)
+	}
+
+	err = q.UpdatePost(ctx, database.UpdatePostParams{
+		ID:          postID,
+		Title:       item.Title,
+		Url:         item.Link,
+		Description: description,
+		Content:     content,
+		ContentHash: hash,
+		Author:      nullString(item.Creator),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update post '%s': %w", item.Title, err)
+	}
+	fmt.Printf("✏️  Updated post: %s\n", item.Title)
+	return nil
+}
+
+// saveEnclosures stores the media enclosures of a post, skipping those it
+// already has.
+func saveEnclosures(ctx context.Context, q *database.Queries, postID uuid.UUID, item rss.RSSItem) error {
+	// The iTunes fields describe the episode as a whole
+	var duration, episode sql.NullInt32
+	if seconds, ok := rss.ParseDuration(item.ITunesDuration); ok {
+		duration = sql.NullInt32{Int32: int32(seconds), Valid: true}
+	}
+	if n, err := strconv.Atoi(strings.TrimSpace(item.ITunesEpisode)); err == nil {
+		episode = sql.NullInt32{Int32: int32(n), Valid: true}
+	}
+	imageURL := strings.TrimSpace(item.ITunesImage.Href)
+
+	for _, enclosure := range item.Enclosures {
+		if enclosure.URL == "" {
+			continue
+		}
+
+		var length sql.NullInt64
+		if n, err := strconv.ParseInt(strings.TrimSpace(enclosure.Length), 10, 64); err == nil && n > 0 {
+			length = sql.NullInt64{Int64: n, Valid: true}
+		}
+
+		now := time.Now()
+		err := q.CreatePostEnclosure(ctx, database.CreatePostEnclosureParams{
+			ID:              uuid.New(),
+			CreatedAt:       now,
+			UpdatedAt:       now,
+			PostID:          postID,
+			Url:             enclosure.URL,
+			Length:          length,
+			MimeType:        sql.NullString{String: enclosure.Type, Valid: enclosure.Type != ""},
+			DurationSeconds: duration,
+			Episode:         episode,
+			ImageUrl:        sql.NullString{String: imageURL, Valid: imageURL != ""},
+		})
 		if err != nil {
-			log.Printf("Error inserting post '%s': %v\n", item.Title, err)
+			return fmt.Errorf("failed to insert enclosure '%s': %w", enclosure.URL, err)
 		}
 	}
+	return nil
 }
@@ -0,0 +1,397 @@
+package cli
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmacneill66/go_projects/gator/internal/config"
+	"github.com/jmacneill66/go_projects/gator/internal/database"
+	"github.com/jmacneill66/go_projects/gator/internal/rss"
+)
+
+// fakePost is a post saved to a fakePosts store.
+type fakePost struct {
+	id, guid, title, hash string
+	author, content       driver.Value
+	revisions             int
+	enclosures            map[string]bool
+}
+
+// fakePosts keeps the posts of one feed in memory, answering the queries
+// that savePosts and scrapeFeed make about them.
+type fakePosts struct {
+	byGuid map[string]*fakePost
+	byID   map[string]*fakePost
+
+	// legacyAdopted is set once the feed stops looking for posts keyed by
+	// their URL
+	legacyAdopted bool
+}
+
+// newFakePosts installs an empty post store on a fake database.
+func newFakePosts(db *fakeDB) *fakePosts {
+	p := &fakePosts{byGuid: map[string]*fakePost{}, byID: map[string]*fakePost{}}
+	db.handle("FeedHasLegacyPosts", func(args []driver.Value) ([]string, [][]driver.Value, error) {
+		legacy := false
+		for guid := range p.byGuid {
+			legacy = legacy || strings.HasPrefix(guid, "legacy:")
+		}
+		return fakeRow([]string{"exists"}, legacy && !p.legacyAdopted)
+	})
+	db.handle("AdoptLegacyPost", func(args []driver.Value) ([]string, [][]driver.Value, error) {
+		guid, legacyGuid := args[0].(string), args[2].(string)
+		post, ok := p.byGuid[legacyGuid]
+		if _, taken := p.byGuid[guid]; ok && !taken {
+			delete(p.byGuid, legacyGuid)
+			post.guid, p.byGuid[guid] = guid, post
+		}
+		return nil, nil, nil
+	})
+	db.handle("MarkLegacyPostsAdopted", func(args []driver.Value) ([]string, [][]driver.Value, error) {
+		p.legacyAdopted = true
+		return nil, nil, nil
+	})
+	db.handle("GetPostByGuid", func(args []driver.Value) ([]string, [][]driver.Value, error) {
+		post, ok := p.byGuid[args[1].(string)]
+		if !ok {
+			return nil, nil, nil
+		}
+		return fakeRow([]string{"id", "content_hash", "has_content"}, post.id, post.hash, post.content != nil)
+	})
+	db.handle("CreatePost", func(args []driver.Value) ([]string, [][]driver.Value, error) {
+		guid := args[9].(string)
+		if _, ok := p.byGuid[guid]; ok {
+			return nil, nil, nil // ON CONFLICT DO NOTHING
+		}
+		post := &fakePost{
+			id:         args[0].(string),
+			guid:       guid,
+			title:      args[3].(string),
+			hash:       args[10].(string),
+			author:     args[11],
+			content:    args[8],
+			enclosures: map[string]bool{},
+		}
+		p.byGuid[guid], p.byID[post.id] = post, post
+		return fakeRow([]string{"id"}, post.id)
+	})
+	db.handle("CreatePostRevision", func(args []driver.Value) ([]string, [][]driver.Value, error) {
+		p.byID[args[2].(string)].revisions++
+		return nil, nil, nil
+	})
+	db.handle("UpdatePost", func(args []driver.Value) ([]string, [][]driver.Value, error) {
+		post := p.byID[args[0].(string)]
+		post.title, post.content, post.hash, post.author = args[1].(string), args[4], args[5].(string), args[6]
+		return nil, nil, nil
+	})
+	db.handle("UpdatePostContent", func(args []driver.Value) ([]string, [][]driver.Value, error) {
+		post := p.byID[args[0].(string)]
+		post.content, post.hash = args[1], args[2].(string)
+		return nil, nil, nil
+	})
+	db.handle("CreatePostEnclosure", func(args []driver.Value) ([]string, [][]driver.Value, error) {
+		p.byID[args[3].(string)].enclosures[args[4].(string)] = true
+		return nil, nil, nil
+	})
+	db.handle("GetFeedPostingStats", func(args []driver.Value) ([]string, [][]driver.Value, error) {
+		return fakeRow([]string{"post_count", "span_seconds", "since_last_seconds"}, int64(len(p.byID)), 0.0, 0.0)
+	})
+	return p
+}
+
+// newTestState returns a State backed by a fake database and fixtures.
+func newTestState(t *testing.T, fixtures *rss.FixtureFetcher) (*State, *fakeDB) {
+	t.Helper()
+	fake, db := newFakeDB(t)
+	client, err := rss.NewClient(config.FetchConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &State{
+		Cfg:        &config.Config{},
+		DB:         database.New(db),
+		Conn:       db,
+		Client:     client,
+		Fetcher:    fixtures,
+		Discoverer: fixtures,
+	}, fake
+}
+
+// rssFixture returns an RSS 2.0 fixture holding the given items.
+func rssFixture(items ...string) rss.Fixture {
+	return rss.Fixture{
+		ContentType: "application/rss+xml",
+		Body: `<?xml version="1.0"?>
+<rss version="2.0" xmlns:dc="http://purl.org/dc/elements/1.1/">
+<channel><title>Example</title><link>https://example.com/</link>` +
+			strings.Join(items, "\n") + `</channel></rss>`,
+	}
+}
+
+func TestScrapeFeed(t *testing.T) {
+	const feedURL = "https://example.com/feed.xml"
+	first := `<item><guid>1</guid><title>First</title><description>One</description>
+<dc:creator>Ada</dc:creator><enclosure url="https://example.com/1.mp3" length="10" type="audio/mpeg"/></item>`
+	second := `<item><guid>2</guid><title>Second</title><description>Two</description></item>`
+	// The same item listed twice with another body must not be taken for an edit
+	secondCopy := `<item><guid>2</guid><title>Second</title><description>Copy</description></item>`
+
+	initial := rssFixture(first, second, secondCopy)
+	fixtures := rss.NewFixtureFetcher(map[string]rss.Fixture{feedURL: initial})
+	s, db := newTestState(t, fixtures)
+	posts := newFakePosts(db)
+	feed := database.ClaimFeedsToFetchRow{ID: uuid.New(), Name: "Example", Url: feedURL}
+	ctx := context.Background()
+
+	stats, err := scrapeFeed(ctx, s, feed)
+	if err != nil {
+		t.Fatalf("first fetch: %v", err)
+	}
+	if want := (fetchStats{statusCode: 200, bytes: int64(len(initial.Body)), items: 3, newPosts: 2}); stats != want {
+		t.Errorf("first fetch: got %+v, want %+v", stats, want)
+	}
+	if got := posts.byGuid["1"]; got == nil || got.author != "Ada" || !got.enclosures["https://example.com/1.mp3"] {
+		t.Errorf("first post: got %+v, want author Ada and its enclosure", got)
+	}
+	if db.called("COMMIT") != 1 {
+		t.Errorf("first fetch was not committed")
+	}
+
+	// The publisher edits the first post and attaches an enclosure to the second
+	edited := strings.Replace(first, "<description>One", "<description>One, edited", 1)
+	withEnclosure := strings.Replace(second, "</item>", `<enclosure url="https://example.com/2.mp3" type="audio/mpeg"/></item>`, 1)
+	fixtures.Set(feedURL, rssFixture(edited, withEnclosure, secondCopy))
+	for i, wantUpdated := range []int{1, 0} {
+		stats, err = scrapeFeed(ctx, s, feed)
+		if err != nil {
+			t.Fatalf("fetch %d after edit: %v", i+1, err)
+		}
+		if stats.newPosts != 0 || stats.updatedPosts != wantUpdated {
+			t.Errorf("fetch %d after edit: got %d new and %d updated posts, want 0 and %d",
+				i+1, stats.newPosts, stats.updatedPosts, wantUpdated)
+		}
+	}
+	if got := posts.byGuid["1"].revisions; got != 1 {
+		t.Errorf("edited post has %d revisions, want 1", got)
+	}
+	if got := posts.byGuid["2"].revisions; got != 0 {
+		t.Errorf("duplicated post has %d revisions, want 0", got)
+	}
+	if !posts.byGuid["2"].enclosures["https://example.com/2.mp3"] {
+		t.Errorf("enclosure added to a saved post was not saved")
+	}
+
+	// A fetch with the current validators is not modified
+	notModified := rssFixture(edited)
+	notModified.ETag = `"v2"`
+	fixtures.Set(feedURL, notModified)
+	feed.Etag.String, feed.Etag.Valid = `"v2"`, true
+	created := db.called("CreatePost")
+	stats, err = scrapeFeed(ctx, s, feed)
+	if err != nil {
+		t.Fatalf("conditional fetch: %v", err)
+	}
+	if stats.statusCode != 304 || db.called("CreatePost") != created {
+		t.Errorf("conditional fetch: got status %d and %d new inserts, want 304 and none",
+			stats.statusCode, db.called("CreatePost")-created)
+	}
+}
+
+func TestScrapeFeedLegacyContent(t *testing.T) {
+	const feedURL = "https://example.com/feed.xml"
+	fixtures := rss.NewFixtureFetcher(map[string]rss.Fixture{feedURL: rssFixture(
+		`<item xmlns:content="http://purl.org/rss/1.0/modules/content/"><guid>1</guid><title>First</title>
+<description>One</description><content:encoded>Full text</content:encoded></item>`,
+		`<item xmlns:content="http://purl.org/rss/1.0/modules/content/"><guid>2</guid><title>Second</title>
+<description>Two, edited</description><content:encoded>Full text</content:encoded></item>`,
+	)})
+	s, db := newTestState(t, fixtures)
+	posts := newFakePosts(db)
+
+	// Posts saved before content was captured, hashed as the migration did
+	for _, post := range []*fakePost{
+		{id: uuid.NewString(), guid: "1", title: "First", hash: contentHash("First", "One", "")},
+		{id: uuid.NewString(), guid: "2", title: "Second", hash: contentHash("Second", "Two", "")},
+	} {
+		posts.byGuid[post.guid], posts.byID[post.id] = post, post
+	}
+
+	stats, err := scrapeFeed(context.Background(), s, database.ClaimFeedsToFetchRow{ID: uuid.New(), Name: "Example", Url: feedURL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.updatedPosts != 1 {
+		t.Errorf("got %d updated posts, want 1", stats.updatedPosts)
+	}
+
+	// Content alone is adopted silently, but other changes are still edits
+	first, second := posts.byGuid["1"], posts.byGuid["2"]
+	if first.revisions != 0 || first.content != "Full text" || first.hash != contentHash("First", "One", "Full text") {
+		t.Errorf("unchanged post: got %d revisions, content %v and hash %s; want its content adopted without a revision",
+			first.revisions, first.content, first.hash)
+	}
+	if second.revisions != 1 || second.content != "Full text" {
+		t.Errorf("edited post: got %d revisions and content %v, want 1 revision", second.revisions, second.content)
+	}
+}
+
+func TestScrapeFeedLegacyPosts(t *testing.T) {
+	const feedURL = "https://example.com/feed.xml"
+	fixtures := rss.NewFixtureFetcher(map[string]rss.Fixture{feedURL: rssFixture(
+		`<item><guid>1</guid><title>First</title><link>https://example.com/1</link></item>`,
+	)})
+	s, db := newTestState(t, fixtures)
+	posts := newFakePosts(db)
+
+	// Posts saved before guids were stored, one still listed by the feed
+	// and one that has rolled off it
+	for _, post := range []*fakePost{
+		{id: uuid.NewString(), guid: "legacy:https://example.com/1", title: "First", hash: contentHash("First", "", "")},
+		{id: uuid.NewString(), guid: "legacy:https://example.com/old", title: "Old", hash: contentHash("Old", "", "")},
+	} {
+		posts.byGuid[post.guid], posts.byID[post.id] = post, post
+	}
+	feed := database.ClaimFeedsToFetchRow{ID: uuid.New(), Name: "Example", Url: feedURL}
+
+	stats, err := scrapeFeed(context.Background(), s, feed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.newPosts != 0 || posts.byGuid["1"] == nil || posts.byGuid["1"].title != "First" {
+		t.Errorf("listed post was not adopted: got %d new posts and %+v", stats.newPosts, posts.byGuid["1"])
+	}
+
+	// Once a full fetch has been saved, the rolled off post is no longer
+	// looked for
+	adopted := db.called("AdoptLegacyPost")
+	for i := 0; i < 2; i++ {
+		if _, err := scrapeFeed(context.Background(), s, feed); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := db.called("AdoptLegacyPost") - adopted; got != 0 {
+		t.Errorf("later fetches tried to adopt %d posts, want none", got)
+	}
+	if posts.byGuid["legacy:https://example.com/old"] == nil {
+		t.Error("rolled off post was removed")
+	}
+}
+
+func TestScrapeFeedsShutdown(t *testing.T) {
+	claimed := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	fixtures := rss.NewFixtureFetcher(nil)
+	s, db := newTestState(t, fixtures)
+	posts := newFakePosts(db)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var rows [][]driver.Value
+	for i := range 3 {
+		feedURL := fmt.Sprintf("https://example.com/%d.xml", i)
+		fixtures.Set(feedURL, rssFixture(`<item><guid>1</guid><title>First</title></item>`))
+		rows = append(rows, []driver.Value{uuid.NewString(), feedURL, "Example", nil, nil, nil, nil, nil,
+			[]byte("{}"), nil, nil, claimed, nil})
+	}
+	db.handle("ClaimFeedsToFetch", func(args []driver.Value) ([]string, [][]driver.Value, error) {
+		return []string{"id", "url", "name", "etag", "last_modified", "auth_type", "auth_username", "auth_secret_env",
+			"headers", "min_fetch_interval_seconds", "max_fetch_interval_seconds",
+			"claimed_last_fetched_at", "claimed_next_fetch_at"}, rows, nil
+	})
+
+	// The shutdown begins while the first feed is being saved
+	hasLegacyPosts := db.handlers["FeedHasLegacyPosts"]
+	db.handle("FeedHasLegacyPosts", func(args []driver.Value) ([]string, [][]driver.Value, error) {
+		cancel()
+		return hasLegacyPosts(args)
+	})
+	released := map[string]bool{}
+	db.handle("ReleaseFeedClaim", func(args []driver.Value) ([]string, [][]driver.Value, error) {
+		if !args[0].(time.Time).Equal(claimed) || args[1] != nil {
+			t.Errorf("released claim with schedule %v, %v; want the one from before the claim", args[0], args[1])
+		}
+		released[args[2].(string)] = true
+		return nil, nil, nil
+	})
+
+	summary, errs := ScrapeFeeds(ctx, s, 1, 3)
+	if len(errs) != 0 {
+		t.Errorf("got errors %v, want none", errs)
+	}
+	if summary.Interrupted != 3 || summary.Feeds != 0 {
+		t.Errorf("got %d feeds fetched and %d interrupted, want 0 and 3", summary.Feeds, summary.Interrupted)
+	}
+
+	// The feed in flight is rolled back and due again by its claim, and the
+	// feeds never started are due again at once
+	if len(released) != 2 || released[rows[0][0].(string)] {
+		t.Errorf("released the claims of %v, want those of the two feeds not started", released)
+	}
+	if len(posts.byGuid) != 0 || db.called("COMMIT") != 0 {
+		t.Errorf("an interrupted fetch was saved")
+	}
+}
+
+func TestScrapeFeedParseError(t *testing.T) {
+	const feedURL = "https://example.com/broken.xml"
+	body := "<html><body>Not a feed</body></html>"
+	fixtures := rss.NewFixtureFetcher(map[string]rss.Fixture{
+		feedURL: {ContentType: "text/html", Body: body},
+	})
+	s, db := newTestState(t, fixtures)
+	newFakePosts(db)
+
+	stats, err := scrapeFeed(context.Background(), s, database.ClaimFeedsToFetchRow{ID: uuid.New(), Name: "Broken", Url: feedURL})
+	if err == nil {
+		t.Fatal("got no error for a body that is not a feed")
+	}
+	if stats.statusCode != 200 || stats.bytes != int64(len(body)) {
+		t.Errorf("got status %d and %d bytes, want 200 and %d", stats.statusCode, stats.bytes, len(body))
+	}
+	if db.called("COMMIT") != 0 {
+		t.Errorf("a failed fetch was committed")
+	}
+}
+
+func TestDiscoverFeedURL(t *testing.T) {
+	feed := rssFixture()
+	fixtures := rss.NewFixtureFetcher(map[string]rss.Fixture{
+		"https://example.com/": {ContentType: "text/html", Body: `<html><head>
+<link rel="alternate" type="application/rss+xml" title="Posts" href="/posts.xml">
+</head></html>`},
+		"https://example.com/posts.xml":      feed,
+		"https://quiet.example.com/":         {ContentType: "text/html", Body: "<html><body>No links</body></html>"},
+		"https://quiet.example.com/atom.xml": feed,
+		"https://example.com/feed.xml":       feed,
+	})
+	s, _ := newTestState(t, fixtures)
+
+	tests := []struct {
+		pageURL string
+		want    string
+	}{
+		{"https://example.com/", "https://example.com/posts.xml"},
+		{"https://quiet.example.com/", "https://quiet.example.com/atom.xml"},
+		{"https://example.com/feed.xml", "https://example.com/feed.xml"},
+		{"file:///tmp/feed.xml", "file:///tmp/feed.xml"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pageURL, func(t *testing.T) {
+			got, err := discoverFeedURL(context.Background(), s, tt.pageURL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := discoverFeedURL(context.Background(), s, "https://missing.example.com/"); err == nil {
+		t.Error("got no error for a page without a fixture")
+	}
+}
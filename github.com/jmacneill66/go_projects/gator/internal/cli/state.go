@@ -1,12 +1,46 @@
 package cli
 
 import (
+	"context"
+	"database/sql"
+	"fmt"
+
 	"github.com/jmacneill66/go_projects/gator/internal/config"
 	"github.com/jmacneill66/go_projects/gator/internal/database"
+	"github.com/jmacneill66/go_projects/gator/internal/rss"
 )
 
-// State struct holds a pointer to the Config.
+// State struct holds a pointer to the Config, the database queries and
+// connection, the shared HTTP client, and the Fetcher and Discoverer used to
+// fetch and find feeds.
 type State struct {
-	Cfg *config.Config
-	DB  *database.Queries
+	Cfg    *config.Config
+	DB     *database.Queries
+	Conn   *sql.DB
+	Client *rss.Client
+
+	// Fetcher fetches feeds by URL; tests can replace it with fixtures
+	Fetcher rss.Fetcher
+
+	// Discoverer finds the feeds of web pages; tests can replace it with
+	// fixtures
+	Discoverer rss.Discoverer
+}
+
+// inTx runs fn with queries bound to a transaction, committing it when fn
+// succeeds. The transaction is rolled back when fn fails or ctx is cancelled.
+func (s *State) inTx(ctx context.Context, fn func(q *database.Queries) error) error {
+	tx, err := s.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := fn(s.DB.WithTx(tx)); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
 }
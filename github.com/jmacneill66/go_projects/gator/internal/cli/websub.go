@@ -0,0 +1,257 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmacneill66/go_projects/gator/internal/database"
+	"github.com/jmacneill66/go_projects/gator/internal/rss"
+	"github.com/jmacneill66/go_projects/gator/internal/websub"
+)
+
+// WebSub defaults, used when the config leaves them unset
+const (
+	defaultWebSubListen = ":8080"
+	defaultWebSubLease  = 7 * 24 * time.Hour
+)
+
+// websubPath is the path of the callback listener. Each subscription gets
+// its own callback URL below it, ending in the subscription ID.
+const websubPath = "/websub/"
+
+// webSubShutdownTimeout bounds how long a shutdown waits for pushes that
+// are still being saved.
+const webSubShutdownTimeout = 10 * time.Second
+
+// startWebSub starts the WebSub callback listener in the background. The
+// returned function shuts it down, waiting until pushes that are still
+// being saved are done or ctx expires.
+func startWebSub(s *State) (func(ctx context.Context) error, error) {
+	addr := s.Cfg.WebSub.Listen
+	if addr == "" {
+		addr = defaultWebSubListen
+	}
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start WebSub listener: %w", err)
+	}
+
+	fmt.Printf("📡 Listening for WebSub pushes on %s\n", listener.Addr())
+	return serveWebSub(s, listener), nil
+}
+
+// serveWebSub serves WebSub callbacks on listener in the background, and
+// returns the function that shuts the server down.
+func serveWebSub(s *State, listener net.Listener) func(ctx context.Context) error {
+	server := &http.Server{Handler: webSubHandler(s), ReadHeaderTimeout: 10 * time.Second}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("WebSub listener stopped: %v\n", err)
+		}
+	}()
+
+	return func(ctx context.Context) error {
+		err := server.Shutdown(ctx)
+		<-done
+		return err
+	}
+}
+
+// webSubHandler routes hubs' verification requests and pushes to the
+// subscription named in the callback URL.
+func webSubHandler(s *State) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET "+websubPath+"{id}", func(w http.ResponseWriter, r *http.Request) {
+		handleWebSubVerification(s, w, r)
+	})
+	mux.HandleFunc("POST "+websubPath+"{id}", func(w http.ResponseWriter, r *http.Request) {
+		handleWebSubContent(s, w, r)
+	})
+	return mux
+}
+
+// handleWebSubVerification answers a hub's request to confirm that we asked
+// for a subscription, or its notice that the subscription was denied.
+func handleWebSubVerification(s *State, w http.ResponseWriter, r *http.Request) {
+	sub, ok := lookupWebSubSubscription(s, w, r)
+	if !ok {
+		return
+	}
+
+	query := r.URL.Query()
+	switch query.Get("hub.mode") {
+	case "subscribe":
+		if query.Get("hub.topic") != sub.TopicUrl {
+			http.NotFound(w, r)
+			return
+		}
+
+		lease := int(leaseOrDefault(s).Seconds())
+		if seconds, err := strconv.Atoi(query.Get("hub.lease_seconds")); err == nil && seconds > 0 {
+			lease = seconds
+		}
+		err := s.DB.VerifyWebSubSubscription(r.Context(), database.VerifyWebSubSubscriptionParams{
+			LeaseSeconds: int32(lease),
+			ID:           sub.ID,
+		})
+		if err != nil {
+			log.Printf("Error verifying WebSub subscription: %v\n", err)
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		fmt.Printf("📡 WebSub subscription verified: %s\n", sub.TopicUrl)
+		fmt.Fprint(w, query.Get("hub.challenge"))
+
+	case "denied":
+		log.Printf("WebSub subscription to %s denied: %s\n", sub.TopicUrl, query.Get("hub.reason"))
+		if err := s.DB.DeleteWebSubSubscription(r.Context(), sub.ID); err != nil {
+			log.Printf("Error deleting WebSub subscription: %v\n", err)
+		}
+		w.WriteHeader(http.StatusOK)
+
+	default:
+		// We never unsubscribe, so any other mode is not ours to confirm
+		http.NotFound(w, r)
+	}
+}
+
+// handleWebSubContent saves the posts in content pushed by a hub.
+func handleWebSubContent(s *State, w http.ResponseWriter, r *http.Request) {
+	sub, ok := lookupWebSubSubscription(s, w, r)
+	if !ok {
+		return
+	}
+
+	body, err := s.Client.ReadBody(r.Body)
+	if err != nil {
+		log.Printf("Error reading WebSub push for %s: %v\n", sub.TopicUrl, err)
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+
+	// Content with a bad signature must still be acknowledged, but is ignored
+	if !websub.ValidSignature(sub.Secret, body, r.Header.Get("X-Hub-Signature")) {
+		log.Printf("Ignoring WebSub push for %s with an invalid signature\n", sub.TopicUrl)
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	feed, err := s.Client.Parse(bytes.NewReader(body), r.Header.Get("Content-Type"))
+	if err != nil {
+		log.Printf("Error parsing WebSub push for %s: %v\n", sub.TopicUrl, err)
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+
+	fmt.Printf("\n📡 Received %d pushed items for %s\n", len(feed.Channel.Item), sub.TopicUrl)
+	err = s.inTx(r.Context(), func(q *database.Queries) error {
+		_, _, err := savePosts(r.Context(), q, sub.FeedID, feed)
+		return err
+	})
+	if err != nil {
+		// Hubs retry content that is not acknowledged
+		log.Printf("Error saving WebSub push for %s: %v\n", sub.TopicUrl, err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// lookupWebSubSubscription returns the subscription named in the callback
+// URL, answering 404 Not Found when there is none.
+func lookupWebSubSubscription(s *State, w http.ResponseWriter, r *http.Request) (database.WebsubSubscription, bool) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		http.NotFound(w, r)
+		return database.WebsubSubscription{}, false
+	}
+	sub, err := s.DB.GetWebSubSubscription(r.Context(), id)
+	if err != nil {
+		http.NotFound(w, r)
+		return database.WebsubSubscription{}, false
+	}
+	return sub, true
+}
+
+// subscribeWebSub subscribes to the hub a feed advertises, or renews the
+// subscription when its lease is about to run out. It does nothing unless
+// a WebSub callback URL is configured.
+func subscribeWebSub(ctx context.Context, s *State, feedID uuid.UUID, feedURL string, rssFeed *rss.RSSFeed) {
+	hub := rssFeed.Channel.Hub
+	if s.Cfg.WebSub.CallbackURL == "" || hub == "" {
+		return
+	}
+
+	// Hubs know the feed by its self URL, when it has one
+	topic := rssFeed.Channel.Self
+	if topic == "" {
+		topic = feedURL
+	}
+
+	existing, err := s.DB.GetWebSubSubscriptionByFeed(ctx, feedID)
+	if err == nil && existing.HubUrl == hub && existing.TopicUrl == topic && !existing.RenewalDue {
+		return
+	}
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		log.Printf("Error looking up WebSub subscription: %v\n", err)
+		return
+	}
+
+	// The secret is only used for new subscriptions; renewals keep theirs
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		log.Printf("Error generating WebSub secret: %v\n", err)
+		return
+	}
+	now := time.Now()
+	sub, err := s.DB.UpsertWebSubSubscription(ctx, database.UpsertWebSubSubscriptionParams{
+		ID:        uuid.New(),
+		CreatedAt: now,
+		UpdatedAt: now,
+		FeedID:    feedID,
+		HubUrl:    hub,
+		TopicUrl:  topic,
+		Secret:    hex.EncodeToString(secret),
+	})
+	if err != nil {
+		log.Printf("Error saving WebSub subscription: %v\n", err)
+		return
+	}
+
+	err = websub.Subscribe(ctx, s.Client.HTTPClient(), websub.Request{
+		Hub:          hub,
+		Topic:        topic,
+		Callback:     strings.TrimSuffix(s.Cfg.WebSub.CallbackURL, "/") + websubPath + sub.ID.String(),
+		Secret:       sub.Secret,
+		LeaseSeconds: int(leaseOrDefault(s).Seconds()),
+	})
+	if err != nil {
+		log.Printf("Error subscribing to %s via %s: %v\n", topic, hub, err)
+		return
+	}
+	fmt.Printf("📡 Requested WebSub subscription to %s via %s\n", topic, hub)
+}
+
+// leaseOrDefault returns the configured WebSub lease.
+func leaseOrDefault(s *State) time.Duration {
+	if s.Cfg.WebSub.Lease.Duration > 0 {
+		return s.Cfg.WebSub.Lease.Duration
+	}
+	return defaultWebSubLease
+}
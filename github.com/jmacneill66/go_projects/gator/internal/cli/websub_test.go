@@ -0,0 +1,271 @@
+package cli
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql/driver"
+	"encoding/hex"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmacneill66/go_projects/gator/internal/rss"
+	"github.com/jmacneill66/go_projects/gator/internal/websub"
+)
+
+// fakeSubscription is a WebSub subscription saved to a fake database.
+type fakeSubscription struct {
+	id, feedID, hub, topic, secret string
+	leaseSeconds                   int64
+}
+
+// newFakeSubscriptions installs an empty WebSub subscription table on a
+// fake database.
+func newFakeSubscriptions(db *fakeDB) map[string]*fakeSubscription {
+	subs := map[string]*fakeSubscription{}
+	db.handle("UpsertWebSubSubscription", func(args []driver.Value) ([]string, [][]driver.Value, error) {
+		sub := &fakeSubscription{
+			id:     args[0].(string),
+			feedID: args[3].(string),
+			hub:    args[4].(string),
+			topic:  args[5].(string),
+			secret: args[6].(string),
+		}
+		subs[sub.id] = sub
+		return fakeRow([]string{"id", "secret"}, sub.id, sub.secret)
+	})
+	db.handle("GetWebSubSubscription", func(args []driver.Value) ([]string, [][]driver.Value, error) {
+		sub, ok := subs[args[0].(string)]
+		if !ok {
+			return nil, nil, nil
+		}
+		columns := []string{"id", "created_at", "updated_at", "feed_id", "hub_url", "topic_url", "secret", "verified_at", "lease_expires_at"}
+		now := time.Now()
+		return fakeRow(columns, sub.id, now, now, sub.feedID, sub.hub, sub.topic, sub.secret, nil, nil)
+	})
+	db.handle("VerifyWebSubSubscription", func(args []driver.Value) ([]string, [][]driver.Value, error) {
+		subs[args[1].(string)].leaseSeconds = args[0].(int64)
+		return nil, nil, nil
+	})
+	return subs
+}
+
+// testHub is a WebSub hub that accepts subscription requests, and calls
+// back subscribers when the test asks it to.
+type testHub struct {
+	*httptest.Server
+	requests chan url.Values
+}
+
+// newTestHub starts a hub that queues the subscription requests it gets.
+func newTestHub(t *testing.T) *testHub {
+	hub := &testHub{requests: make(chan url.Values, 1)}
+	hub.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		hub.requests <- r.PostForm
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	t.Cleanup(hub.Close)
+	return hub
+}
+
+// verify asks the callback to confirm a subscription, returning the
+// response status and body.
+func (h *testHub) verify(t *testing.T, callback, topic, challenge string) (int, string) {
+	t.Helper()
+	query := url.Values{
+		"hub.mode":          {"subscribe"},
+		"hub.topic":         {topic},
+		"hub.challenge":     {challenge},
+		"hub.lease_seconds": {"3600"},
+	}
+	resp, err := http.Get(callback + "?" + query.Encode())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode, string(body)
+}
+
+// push sends content to the callback with the given signature, returning
+// the response status.
+func (h *testHub) push(t *testing.T, callback, body, signature string) int {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodPost, callback, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/rss+xml")
+	req.Header.Set("X-Hub-Signature", signature)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+// sign returns the X-Hub-Signature of a body.
+func sign(secret, body string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(body))
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestWebSub(t *testing.T) {
+	const topic = "https://example.com/feed.xml"
+	s, db := newTestState(t, rss.NewFixtureFetcher(nil))
+	posts := newFakePosts(db)
+	subs := newFakeSubscriptions(db)
+	callbacks := httptest.NewServer(webSubHandler(s))
+	defer callbacks.Close()
+	s.Cfg.WebSub.CallbackURL = callbacks.URL
+	hub := newTestHub(t)
+
+	// Subscribe to the hub the feed advertises
+	feed := &rss.RSSFeed{}
+	feed.Channel.Hub = hub.URL
+	feed.Channel.Self = topic
+	subscribeWebSub(context.Background(), s, uuid.New(), topic, feed)
+
+	var form url.Values
+	select {
+	case form = <-hub.requests:
+	default:
+		t.Fatal("hub got no subscription request")
+	}
+	callback, secret := form.Get("hub.callback"), form.Get("hub.secret")
+	if form.Get("hub.mode") != "subscribe" || form.Get("hub.topic") != topic ||
+		!strings.HasPrefix(callback, callbacks.URL+websubPath) || secret == "" {
+		t.Fatalf("unexpected subscription request: %v", form)
+	}
+	sub := subs[strings.TrimPrefix(callback, callbacks.URL+websubPath)]
+	if sub == nil {
+		t.Fatalf("subscription for callback %s was not saved", callback)
+	}
+
+	// A verification of another topic is refused
+	if status, body := hub.verify(t, callback, "https://example.com/other.xml", "nope"); status != http.StatusNotFound || body == "nope" {
+		t.Errorf("verification of another topic: got %d %q, want 404", status, body)
+	}
+
+	// The hub verifies the subscription
+	status, body := hub.verify(t, callback, topic, "challenge-123")
+	if status != http.StatusOK || body != "challenge-123" {
+		t.Errorf("verification: got %d %q, want 200 with the challenge echoed", status, body)
+	}
+	if sub.leaseSeconds != 3600 {
+		t.Errorf("stored lease of %d seconds, want 3600", sub.leaseSeconds)
+	}
+
+	// A signed push is saved
+	signed := rssFixture(`<item><guid>signed</guid><title>Signed</title></item>`).Body
+	if status := hub.push(t, callback, signed, sign(secret, signed)); status != http.StatusAccepted {
+		t.Errorf("signed push: got status %d, want 202", status)
+	}
+	if posts.byGuid["signed"] == nil {
+		t.Error("signed push was not saved")
+	}
+
+	// A push with a bad signature is acknowledged but ignored
+	forged := rssFixture(`<item><guid>forged</guid><title>Forged</title></item>`).Body
+	badSignature := sign("wrong secret", forged)
+	if websub.ValidSignature(secret, []byte(forged), badSignature) {
+		t.Error("ValidSignature accepted a signature made with another secret")
+	}
+	if status := hub.push(t, callback, forged, badSignature); status != http.StatusAccepted {
+		t.Errorf("forged push: got status %d, want 202", status)
+	}
+	if posts.byGuid["forged"] != nil {
+		t.Error("push with a bad signature was saved")
+	}
+
+	// Callbacks for unknown subscriptions are not found
+	if status, _ := hub.verify(t, callbacks.URL+websubPath+uuid.NewString(), topic, "x"); status != http.StatusNotFound {
+		t.Errorf("unknown subscription: got status %d, want 404", status)
+	}
+}
+
+func TestWebSubShutdown(t *testing.T) {
+	s, db := newTestState(t, rss.NewFixtureFetcher(nil))
+	posts := newFakePosts(db)
+	subs := newFakeSubscriptions(db)
+	sub := &fakeSubscription{id: uuid.NewString(), feedID: uuid.NewString(), topic: "https://example.com/feed.xml", secret: "s3cret"}
+	subs[sub.id] = sub
+
+	// Hold the push in the middle of saving it
+	saving, release := make(chan struct{}), make(chan struct{})
+	createPost := db.handlers["CreatePost"]
+	db.handle("CreatePost", func(args []driver.Value) ([]string, [][]driver.Value, error) {
+		close(saving)
+		<-release
+		return createPost(args)
+	})
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	shutdown := serveWebSub(s, listener)
+	callback := "http://" + listener.Addr().String() + websubPath + sub.id
+
+	body := rssFixture(`<item><guid>late</guid><title>Late</title></item>`).Body
+	pushed := make(chan int)
+	go func() {
+		req, _ := http.NewRequest(http.MethodPost, callback, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/rss+xml")
+		req.Header.Set("X-Hub-Signature", sign(sub.secret, body))
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			pushed <- 0
+			return
+		}
+		resp.Body.Close()
+		pushed <- resp.StatusCode
+	}()
+	<-saving
+
+	stopped := make(chan error)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		stopped <- shutdown(ctx)
+	}()
+
+	// The shutdown waits for the push being saved
+	select {
+	case err := <-stopped:
+		t.Fatalf("shutdown returned while a push was being saved: %v", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+	close(release)
+
+	if status := <-pushed; status != http.StatusAccepted {
+		t.Errorf("push in flight: got status %d, want 202", status)
+	}
+	if err := <-stopped; err != nil {
+		t.Errorf("shutdown: %v", err)
+	}
+	if posts.byGuid["late"] == nil {
+		t.Error("push in flight was not saved")
+	}
+
+	// No more pushes are accepted
+	if _, err := http.Get(callback); err == nil {
+		t.Error("listener still accepts requests after shutdown")
+	}
+}
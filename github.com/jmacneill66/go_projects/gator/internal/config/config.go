@@ -9,8 +9,43 @@ import (
 
 // Config struct represents the JSON config structure.
 type Config struct {
-	CurrentUserName string `json:"current_user_name"`
-	DBUrl           string `json:"db_url"`
+	CurrentUserName string       `json:"current_user_name"`
+	DBUrl           string       `json:"db_url"`
+	Fetch           FetchConfig  `json:"fetch,omitzero"`
+	WebSub          WebSubConfig `json:"websub,omitzero"`
+}
+
+// FetchConfig holds the settings used to fetch feeds.
+// Zero values fall back to the defaults.
+type FetchConfig struct {
+	ConnectTimeout  Duration `json:"connect_timeout,omitzero"`
+	ReadTimeout     Duration `json:"read_timeout,omitzero"`
+	TotalTimeout    Duration `json:"total_timeout,omitzero"`
+	Proxy           string   `json:"proxy,omitempty"`
+	CABundle        string   `json:"ca_bundle,omitempty"`
+	MaxRedirects    int      `json:"max_redirects,omitempty"`
+	UserAgent       string   `json:"user_agent,omitempty"`
+	MaxIdleConns    int      `json:"max_idle_conns,omitempty"`
+	IdleConnTimeout Duration `json:"idle_conn_timeout,omitzero"`
+	MaxBodyBytes    int64    `json:"max_body_bytes,omitempty"`
+	MaxItems        int      `json:"max_items,omitempty"`
+
+	// DisableAfterFailures is the number of failed fetches in a row after
+	// which agg disables a feed. A negative value never disables feeds.
+	DisableAfterFailures int `json:"disable_after_failures,omitempty"`
+}
+
+// WebSubConfig holds the settings for receiving WebSub pushes while agg
+// runs. WebSub is disabled unless CallbackURL is set.
+type WebSubConfig struct {
+	// Listen is the address of the callback listener, e.g. ":8080"
+	Listen string `json:"listen,omitempty"`
+
+	// CallbackURL is the public base URL at which hubs reach the listener
+	CallbackURL string `json:"callback_url,omitempty"`
+
+	// Lease is the subscription lease to request from hubs
+	Lease Duration `json:"lease,omitzero"`
 }
 
 // File constants
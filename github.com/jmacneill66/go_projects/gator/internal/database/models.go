@@ -6,19 +6,56 @@ package database
 
 import (
 	"database/sql"
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
 )
 
 type Feed struct {
-	ID            uuid.UUID
-	CreatedAt     time.Time
-	UpdatedAt     time.Time
-	Name          string
-	Url           string
-	UserID        uuid.UUID
-	LastFetchedAt sql.NullTime
+	ID                      uuid.UUID
+	CreatedAt               time.Time
+	UpdatedAt               time.Time
+	Name                    string
+	Url                     string
+	UserID                  uuid.UUID
+	LastFetchedAt           sql.NullTime
+	Etag                    sql.NullString
+	LastModified            sql.NullString
+	Title                   sql.NullString
+	Description             sql.NullString
+	SiteUrl                 sql.NullString
+	Language                sql.NullString
+	ImageUrl                sql.NullString
+	Generator               sql.NullString
+	MovedTo                 sql.NullString
+	MovedCount              int32
+	DeadAt                  sql.NullTime
+	AuthType                sql.NullString
+	AuthUsername            sql.NullString
+	AuthSecretEnv           sql.NullString
+	Headers                 json.RawMessage
+	NextFetchAt             sql.NullTime
+	MinFetchIntervalSeconds sql.NullInt32
+	MaxFetchIntervalSeconds sql.NullInt32
+	LastError               sql.NullString
+	LastStatusCode          sql.NullInt32
+	ConsecutiveFailures     int32
+	LastSuccessAt           sql.NullTime
+	DisabledAt              sql.NullTime
+	LegacyPostsAdopted      bool
+}
+
+type FeedFetch struct {
+	ID         uuid.UUID
+	FeedID     uuid.UUID
+	StartedAt  time.Time
+	FinishedAt time.Time
+	StatusCode sql.NullInt32
+	Bytes      int64
+	ItemCount  int32
+	NewPosts   int32
+	Error      sql.NullString
 }
 
 type FeedFollow struct {
@@ -27,6 +64,14 @@ type FeedFollow struct {
 	UpdatedAt time.Time
 	UserID    uuid.UUID
 	FeedID    uuid.UUID
+	Category  sql.NullString
+}
+
+type FeedUrlAlias struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	FeedID    uuid.UUID
+	Url       string
 }
 
 type Post struct {
@@ -38,6 +83,34 @@ type Post struct {
 	Description sql.NullString
 	PublishedAt sql.NullTime
 	FeedID      uuid.UUID
+	Content     sql.NullString
+	Guid        string
+	ContentHash string
+	RevisedAt   sql.NullTime
+	Author      sql.NullString
+}
+
+type PostEnclosure struct {
+	ID              uuid.UUID
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	PostID          uuid.UUID
+	Url             string
+	Length          sql.NullInt64
+	MimeType        sql.NullString
+	DurationSeconds sql.NullInt32
+	Episode         sql.NullInt32
+	ImageUrl        sql.NullString
+}
+
+type PostRevision struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	PostID      uuid.UUID
+	Title       string
+	Description sql.NullString
+	Content     sql.NullString
+	ContentHash string
 }
 
 type User struct {
@@ -46,3 +119,15 @@ type User struct {
 	UpdatedAt time.Time
 	Name      string
 }
+
+type WebsubSubscription struct {
+	ID             uuid.UUID
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	FeedID         uuid.UUID
+	HubUrl         string
+	TopicUrl       string
+	Secret         string
+	VerifiedAt     sql.NullTime
+	LeaseExpiresAt sql.NullTime
+}
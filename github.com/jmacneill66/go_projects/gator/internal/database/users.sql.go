@@ -8,11 +8,126 @@ package database
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+const adoptLegacyPost = `-- name: AdoptLegacyPost :exec
+UPDATE posts
+SET guid = $1, updated_at = now()
+WHERE feed_id = $2 AND guid = $3
+AND NOT EXISTS (
+    SELECT 1 FROM posts AS keyed
+    WHERE keyed.feed_id = $2 AND keyed.guid = $1
+)
+`
+
+type AdoptLegacyPostParams struct {
+	Guid       string
+	FeedID     uuid.UUID
+	LegacyGuid string
+}
+
+// Gives a post keyed by its URL the item's real key, unless another post
+// of the feed already has that key.
+func (q *Queries) AdoptLegacyPost(ctx context.Context, arg AdoptLegacyPostParams) error {
+	_, err := q.db.ExecContext(ctx, adoptLegacyPost, arg.Guid, arg.FeedID, arg.LegacyGuid)
+	return err
+}
+
+const claimFeedsToFetch = `-- name: ClaimFeedsToFetch :many
+UPDATE feeds
+SET last_fetched_at = now(),
+    next_fetch_at = now() + COALESCE(feeds.min_fetch_interval_seconds, $1::integer) * interval '1 second',
+    updated_at = now()
+FROM (
+    SELECT id, last_fetched_at, next_fetch_at FROM feeds
+    WHERE dead_at IS NULL AND disabled_at IS NULL
+    AND (next_fetch_at IS NULL OR next_fetch_at <= now())
+    AND (
+        NOT EXISTS (
+            SELECT 1 FROM websub_subscriptions
+            WHERE websub_subscriptions.feed_id = feeds.id
+            AND websub_subscriptions.lease_expires_at > now()
+        )
+        OR feeds.last_fetched_at < now() - interval '1 day'
+    )
+    ORDER BY next_fetch_at NULLS FIRST, last_fetched_at NULLS FIRST
+    LIMIT $2
+    FOR UPDATE SKIP LOCKED
+) AS due
+WHERE feeds.id = due.id
+RETURNING feeds.id, feeds.url, feeds.name, feeds.etag, feeds.last_modified, feeds.auth_type, feeds.auth_username,
+    feeds.auth_secret_env, feeds.headers, feeds.min_fetch_interval_seconds, feeds.max_fetch_interval_seconds,
+    due.last_fetched_at AS claimed_last_fetched_at, due.next_fetch_at AS claimed_next_fetch_at
+`
+
+type ClaimFeedsToFetchParams struct {
+	DefaultMinSeconds int32
+	BatchSize         int32
+}
+
+type ClaimFeedsToFetchRow struct {
+	ID                      uuid.UUID
+	Url                     string
+	Name                    string
+	Etag                    sql.NullString
+	LastModified            sql.NullString
+	AuthType                sql.NullString
+	AuthUsername            sql.NullString
+	AuthSecretEnv           sql.NullString
+	Headers                 json.RawMessage
+	MinFetchIntervalSeconds sql.NullInt32
+	MaxFetchIntervalSeconds sql.NullInt32
+	ClaimedLastFetchedAt    sql.NullTime
+	ClaimedNextFetchAt      sql.NullTime
+}
+
+// Marks up to batch_size due feeds as fetched and returns them. Rows locked
+// by another agg are skipped, so no feed is claimed twice. Dead and disabled
+// feeds are never fetched, and feeds pushed by a WebSub hub only need an
+// occasional poll. A claimed feed is next due after its minimum interval,
+// until the outcome of its fetch reschedules it. The feed's schedule before
+// the claim is returned too, so that an unused claim can be released.
+func (q *Queries) ClaimFeedsToFetch(ctx context.Context, arg ClaimFeedsToFetchParams) ([]ClaimFeedsToFetchRow, error) {
+	rows, err := q.db.QueryContext(ctx, claimFeedsToFetch, arg.DefaultMinSeconds, arg.BatchSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ClaimFeedsToFetchRow
+	for rows.Next() {
+		var i ClaimFeedsToFetchRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Url,
+			&i.Name,
+			&i.Etag,
+			&i.LastModified,
+			&i.AuthType,
+			&i.AuthUsername,
+			&i.AuthSecretEnv,
+			&i.Headers,
+			&i.MinFetchIntervalSeconds,
+			&i.MaxFetchIntervalSeconds,
+			&i.ClaimedLastFetchedAt,
+			&i.ClaimedNextFetchAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const createFeed = `-- name: CreateFeed :one
 INSERT INTO feeds (id, created_at, updated_at, name, url, user_id)
 VALUES ($1, $2, $3, $4, $5, $6)
@@ -110,10 +225,11 @@ func (q *Queries) CreateFeedFollow(ctx context.Context, arg CreateFeedFollowPara
 	return i, err
 }
 
-const createPost = `-- name: CreatePost :exec
-INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id)
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
-ON CONFLICT (url) DO NOTHING
+const createPost = `-- name: CreatePost :one
+INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id, content, guid, content_hash, author)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+ON CONFLICT (feed_id, guid) DO NOTHING
+RETURNING id
 `
 
 type CreatePostParams struct {
@@ -125,10 +241,14 @@ type CreatePostParams struct {
 	Description sql.NullString
 	PublishedAt sql.NullTime
 	FeedID      uuid.UUID
+	Content     sql.NullString
+	Guid        string
+	ContentHash string
+	Author      sql.NullString
 }
 
-func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) error {
-	_, err := q.db.ExecContext(ctx, createPost,
+func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (uuid.UUID, error) {
+	row := q.db.QueryRowContext(ctx, createPost,
 		arg.ID,
 		arg.CreatedAt,
 		arg.UpdatedAt,
@@ -137,8 +257,14 @@ func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) error {
 		arg.Description,
 		arg.PublishedAt,
 		arg.FeedID,
+		arg.Content,
+		arg.Guid,
+		arg.ContentHash,
+		arg.Author,
 	)
-	return err
+	var id uuid.UUID
+	err := row.Scan(&id)
+	return id, err
 }
 
 const createUser = `-- name: CreateUser :one
@@ -188,7 +314,11 @@ func (q *Queries) DeleteAllUsers(ctx context.Context) error {
 const deleteFeedFollow = `-- name: DeleteFeedFollow :exec
 DELETE FROM feed_follows 
 WHERE user_id = (SELECT id FROM users WHERE users.name = $1)
-AND feed_id = (SELECT id FROM feeds WHERE url = $2)
+AND feed_id IN (
+    SELECT id FROM feeds WHERE url = $2
+    UNION
+    SELECT feed_id FROM feed_url_aliases WHERE feed_url_aliases.url = $2
+)
 `
 
 type DeleteFeedFollowParams struct {
@@ -201,8 +331,29 @@ func (q *Queries) DeleteFeedFollow(ctx context.Context, arg DeleteFeedFollowPara
 	return err
 }
 
+const feedHasLegacyPosts = `-- name: FeedHasLegacyPosts :one
+SELECT EXISTS (
+    SELECT 1 FROM posts
+    JOIN feeds ON posts.feed_id = feeds.id
+    WHERE posts.feed_id = $1 AND posts.guid LIKE 'legacy:%' AND NOT feeds.legacy_posts_adopted
+)
+`
+
+// Reports whether a feed has posts keyed by their URL that may still be
+// adopted.
+func (q *Queries) FeedHasLegacyPosts(ctx context.Context, feedID uuid.UUID) (bool, error) {
+	row := q.db.QueryRowContext(ctx, feedHasLegacyPosts, feedID)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
 const getFeedByUrl = `-- name: GetFeedByUrl :one
-SELECT id, name FROM feeds WHERE url = $1 LIMIT 1
+SELECT id, name FROM feeds
+WHERE url = $1
+OR id IN (SELECT feed_id FROM feed_url_aliases WHERE feed_url_aliases.url = $1)
+ORDER BY url = $1 DESC
+LIMIT 1
 `
 
 type GetFeedByUrlRow struct {
@@ -218,7 +369,9 @@ func (q *Queries) GetFeedByUrl(ctx context.Context, url string) (GetFeedByUrlRow
 }
 
 const getFeedFollowsForUser = `-- name: GetFeedFollowsForUser :many
-SELECT feed_follows.id, feed_follows.created_at, feed_follows.updated_at, users.name AS user_name, feeds.name AS feed_name
+SELECT feed_follows.id, feed_follows.created_at, feed_follows.updated_at, users.name AS user_name, feeds.name AS feed_name,
+    feeds.url AS feed_url, feed_follows.category, feeds.title AS feed_title, feeds.description AS feed_description,
+    feeds.site_url AS feed_site_url
 FROM feed_follows
 JOIN users ON feed_follows.user_id = users.id
 JOIN feeds ON feed_follows.feed_id = feeds.id
@@ -226,11 +379,16 @@ WHERE users.name = $1
 `
 
 type GetFeedFollowsForUserRow struct {
-	ID        uuid.UUID
-	CreatedAt time.Time
-	UpdatedAt time.Time
-	UserName  string
-	FeedName  string
+	ID              uuid.UUID
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	UserName        string
+	FeedName        string
+	FeedUrl         string
+	Category        sql.NullString
+	FeedTitle       sql.NullString
+	FeedDescription sql.NullString
+	FeedSiteUrl     sql.NullString
 }
 
 func (q *Queries) GetFeedFollowsForUser(ctx context.Context, name string) ([]GetFeedFollowsForUserRow, error) {
@@ -248,6 +406,11 @@ func (q *Queries) GetFeedFollowsForUser(ctx context.Context, name string) ([]Get
 			&i.UpdatedAt,
 			&i.UserName,
 			&i.FeedName,
+			&i.FeedUrl,
+			&i.Category,
+			&i.FeedTitle,
+			&i.FeedDescription,
+			&i.FeedSiteUrl,
 		); err != nil {
 			return nil, err
 		}
@@ -263,18 +426,35 @@ func (q *Queries) GetFeedFollowsForUser(ctx context.Context, name string) ([]Get
 }
 
 const getFeedsWithUser = `-- name: GetFeedsWithUser :many
-SELECT feeds.id, feeds.created_at, feeds.updated_at, feeds.name, feeds.url, users.name AS user_name
+SELECT feeds.id, feeds.created_at, feeds.updated_at, feeds.name, feeds.url, users.name AS user_name,
+    feeds.title, feeds.description, feeds.site_url, feeds.language, feeds.image_url, feeds.generator,
+    feeds.dead_at, feeds.auth_type, feeds.headers,
+    feeds.next_fetch_at, feeds.min_fetch_interval_seconds, feeds.max_fetch_interval_seconds,
+    feeds.disabled_at
 FROM feeds
 JOIN users ON feeds.user_id = users.id
 `
 
 type GetFeedsWithUserRow struct {
-	ID        uuid.UUID
-	CreatedAt time.Time
-	UpdatedAt time.Time
-	Name      string
-	Url       string
-	UserName  string
+	ID                      uuid.UUID
+	CreatedAt               time.Time
+	UpdatedAt               time.Time
+	Name                    string
+	Url                     string
+	UserName                string
+	Title                   sql.NullString
+	Description             sql.NullString
+	SiteUrl                 sql.NullString
+	Language                sql.NullString
+	ImageUrl                sql.NullString
+	Generator               sql.NullString
+	DeadAt                  sql.NullTime
+	AuthType                sql.NullString
+	Headers                 json.RawMessage
+	NextFetchAt             sql.NullTime
+	MinFetchIntervalSeconds sql.NullInt32
+	MaxFetchIntervalSeconds sql.NullInt32
+	DisabledAt              sql.NullTime
 }
 
 func (q *Queries) GetFeedsWithUser(ctx context.Context) ([]GetFeedsWithUserRow, error) {
@@ -293,6 +473,19 @@ func (q *Queries) GetFeedsWithUser(ctx context.Context) ([]GetFeedsWithUserRow,
 			&i.Name,
 			&i.Url,
 			&i.UserName,
+			&i.Title,
+			&i.Description,
+			&i.SiteUrl,
+			&i.Language,
+			&i.ImageUrl,
+			&i.Generator,
+			&i.DeadAt,
+			&i.AuthType,
+			&i.Headers,
+			&i.NextFetchAt,
+			&i.MinFetchIntervalSeconds,
+			&i.MaxFetchIntervalSeconds,
+			&i.DisabledAt,
 		); err != nil {
 			return nil, err
 		}
@@ -307,33 +500,87 @@ func (q *Queries) GetFeedsWithUser(ctx context.Context) ([]GetFeedsWithUserRow,
 	return items, nil
 }
 
-const getNextFeedToFetch = `-- name: GetNextFeedToFetch :one
-SELECT id, url, name 
-FROM feeds 
-ORDER BY last_fetched_at NULLS FIRST, updated_at ASC 
-LIMIT 1
+const getPostByGuid = `-- name: GetPostByGuid :one
+SELECT id, content_hash, content IS NOT NULL AS has_content FROM posts
+WHERE feed_id = $1 AND guid = $2
 `
 
-type GetNextFeedToFetchRow struct {
-	ID   uuid.UUID
-	Url  string
+type GetPostByGuidParams struct {
+	FeedID uuid.UUID
+	Guid   string
+}
+
+type GetPostByGuidRow struct {
+	ID          uuid.UUID
+	ContentHash string
+	HasContent  bool
+}
+
+func (q *Queries) GetPostByGuid(ctx context.Context, arg GetPostByGuidParams) (GetPostByGuidRow, error) {
+	row := q.db.QueryRowContext(ctx, getPostByGuid, arg.FeedID, arg.Guid)
+	var i GetPostByGuidRow
+	err := row.Scan(&i.ID, &i.ContentHash, &i.HasContent)
+	return i, err
+}
+
+const getPostForUser = `-- name: GetPostForUser :one
+SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.content,
+    posts.revised_at, posts.author, feeds.url AS feed_url
+FROM posts
+JOIN feeds ON posts.feed_id = feeds.id
+JOIN feed_follows ON posts.feed_id = feed_follows.feed_id
+JOIN users ON feed_follows.user_id = users.id
+WHERE users.name = $1 AND posts.id = $2
+`
+
+type GetPostForUserParams struct {
 	Name string
+	ID   uuid.UUID
 }
 
-func (q *Queries) GetNextFeedToFetch(ctx context.Context) (GetNextFeedToFetchRow, error) {
-	row := q.db.QueryRowContext(ctx, getNextFeedToFetch)
-	var i GetNextFeedToFetchRow
-	err := row.Scan(&i.ID, &i.Url, &i.Name)
+type GetPostForUserRow struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Title       string
+	Url         string
+	Description sql.NullString
+	PublishedAt sql.NullTime
+	FeedID      uuid.UUID
+	Content     sql.NullString
+	RevisedAt   sql.NullTime
+	Author      sql.NullString
+	FeedUrl     string
+}
+
+func (q *Queries) GetPostForUser(ctx context.Context, arg GetPostForUserParams) (GetPostForUserRow, error) {
+	row := q.db.QueryRowContext(ctx, getPostForUser, arg.Name, arg.ID)
+	var i GetPostForUserRow
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Title,
+		&i.Url,
+		&i.Description,
+		&i.PublishedAt,
+		&i.FeedID,
+		&i.Content,
+		&i.RevisedAt,
+		&i.Author,
+		&i.FeedUrl,
+	)
 	return i, err
 }
 
 const getPostsForUser = `-- name: GetPostsForUser :many
-SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id
+SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.content, posts.guid,
+    posts.content_hash, posts.revised_at, posts.author
 FROM posts
 JOIN feed_follows ON posts.feed_id = feed_follows.feed_id
 JOIN users ON feed_follows.user_id = users.id
 WHERE users.name = $1
-ORDER BY posts.published_at DESC
+ORDER BY posts.published_at DESC NULLS LAST, posts.created_at DESC
 LIMIT $2
 `
 
@@ -360,6 +607,11 @@ func (q *Queries) GetPostsForUser(ctx context.Context, arg GetPostsForUserParams
 			&i.Description,
 			&i.PublishedAt,
 			&i.FeedID,
+			&i.Content,
+			&i.Guid,
+			&i.ContentHash,
+			&i.RevisedAt,
+			&i.Author,
 		); err != nil {
 			return nil, err
 		}
@@ -424,13 +676,111 @@ func (q *Queries) GetUsers(ctx context.Context) ([]User, error) {
 	return items, nil
 }
 
-const markFeedFetched = `-- name: MarkFeedFetched :exec
-UPDATE feeds 
-SET last_fetched_at = now(), updated_at = now() 
+const importFeedFollow = `-- name: ImportFeedFollow :exec
+INSERT INTO feed_follows (id, created_at, updated_at, user_id, feed_id, category)
+VALUES ($1, $2, $3, $4, $5, $6)
+ON CONFLICT (user_id, feed_id) DO UPDATE
+SET category = EXCLUDED.category, updated_at = EXCLUDED.updated_at
+`
+
+type ImportFeedFollowParams struct {
+	ID              uuid.UUID
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	UserID          uuid.UUID
+	FeedID          uuid.UUID
+	Category        sql.NullString
+	FeedTitle       sql.NullString
+	FeedDescription sql.NullString
+	FeedSiteUrl     sql.NullString
+}
+
+func (q *Queries) ImportFeedFollow(ctx context.Context, arg ImportFeedFollowParams) error {
+	_, err := q.db.ExecContext(ctx, importFeedFollow,
+		arg.ID,
+		arg.CreatedAt,
+		arg.UpdatedAt,
+		arg.UserID,
+		arg.FeedID,
+		arg.Category,
+	)
+	return err
+}
+
+const markLegacyPostsAdopted = `-- name: MarkLegacyPostsAdopted :exec
+UPDATE feeds SET legacy_posts_adopted = true
+WHERE id = $1 AND NOT legacy_posts_adopted
+`
+
+// Stops looking for posts keyed by their URL once a full fetch of the feed
+// has had the chance to adopt them.
+func (q *Queries) MarkLegacyPostsAdopted(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, markLegacyPostsAdopted, id)
+	return err
+}
+
+const releaseFeedClaim = `-- name: ReleaseFeedClaim :exec
+UPDATE feeds
+SET last_fetched_at = $1, next_fetch_at = $2, updated_at = now()
+WHERE id = $3
+`
+
+type ReleaseFeedClaimParams struct {
+	LastFetchedAt sql.NullTime
+	NextFetchAt   sql.NullTime
+	ID            uuid.UUID
+}
+
+// Restores the schedule of a claimed feed whose fetch never started, so it
+// is due again as before.
+func (q *Queries) ReleaseFeedClaim(ctx context.Context, arg ReleaseFeedClaimParams) error {
+	_, err := q.db.ExecContext(ctx, releaseFeedClaim, arg.LastFetchedAt, arg.NextFetchAt, arg.ID)
+	return err
+}
+
+const updateFeedMetadata = `-- name: UpdateFeedMetadata :exec
+UPDATE feeds
+SET title = $2, description = $3, site_url = $4, language = $5, image_url = $6, generator = $7,
+    updated_at = now()
 WHERE id = $1
 `
 
-func (q *Queries) MarkFeedFetched(ctx context.Context, id uuid.UUID) error {
-	_, err := q.db.ExecContext(ctx, markFeedFetched, id)
+type UpdateFeedMetadataParams struct {
+	ID          uuid.UUID
+	Title       sql.NullString
+	Description sql.NullString
+	SiteUrl     sql.NullString
+	Language    sql.NullString
+	ImageUrl    sql.NullString
+	Generator   sql.NullString
+}
+
+func (q *Queries) UpdateFeedMetadata(ctx context.Context, arg UpdateFeedMetadataParams) error {
+	_, err := q.db.ExecContext(ctx, updateFeedMetadata,
+		arg.ID,
+		arg.Title,
+		arg.Description,
+		arg.SiteUrl,
+		arg.Language,
+		arg.ImageUrl,
+		arg.Generator,
+	)
+	return err
+}
+
+const updateFeedValidators = `-- name: UpdateFeedValidators :exec
+UPDATE feeds
+SET etag = $2, last_modified = $3, updated_at = now()
+WHERE id = $1
+`
+
+type UpdateFeedValidatorsParams struct {
+	ID           uuid.UUID
+	Etag         sql.NullString
+	LastModified sql.NullString
+}
+
+func (q *Queries) UpdateFeedValidators(ctx context.Context, arg UpdateFeedValidatorsParams) error {
+	_, err := q.db.ExecContext(ctx, updateFeedValidators, arg.ID, arg.Etag, arg.LastModified)
 	return err
 }
@@ -0,0 +1,174 @@
+package opml
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html/charset"
+)
+
+// Document represents an OPML 2.0 subscription list.
+type Document struct {
+	XMLName xml.Name `xml:"opml"`
+	Version string   `xml:"version,attr"`
+	Head    Head     `xml:"head"`
+	Body    Body     `xml:"body"`
+}
+
+// Head holds the OPML document metadata.
+type Head struct {
+	Title       string `xml:"title"`
+	DateCreated string `xml:"dateCreated,omitempty"`
+}
+
+// Body holds the top-level outlines.
+type Body struct {
+	Outlines []Outline `xml:"outline"`
+}
+
+// Outline is either a feed (with an xmlUrl) or a folder of outlines.
+type Outline struct {
+	Text     string    `xml:"text,attr"`
+	Title    string    `xml:"title,attr,omitempty"`
+	Type     string    `xml:"type,attr,omitempty"`
+	XMLURL   string    `xml:"xmlUrl,attr,omitempty"`
+	HTMLURL  string    `xml:"htmlUrl,attr,omitempty"`
+	Outlines []Outline `xml:"outline"`
+}
+
+// Feed is a subscription read from or written to an OPML document.
+type Feed struct {
+	Title string
+	URL   string
+	// Category is the folder path, with nested folders separated by "/".
+	// A "/" or "\" within a folder name is escaped with a backslash.
+	Category string
+}
+
+// Parse reads the feeds from an OPML document, keeping the folders they
+// are nested in as their category.
+func Parse(r io.Reader) ([]Feed, error) {
+	decoder := xml.NewDecoder(r)
+	decoder.CharsetReader = charset.NewReaderLabel
+
+	var doc Document
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("failed to parse OPML: %w", err)
+	}
+
+	var feeds []Feed
+	var walk func(outlines []Outline, folders []string)
+	walk = func(outlines []Outline, folders []string) {
+		for _, outline := range outlines {
+			title := strings.TrimSpace(outline.Title)
+			if title == "" {
+				title = strings.TrimSpace(outline.Text)
+			}
+
+			if url := strings.TrimSpace(outline.XMLURL); url != "" {
+				feeds = append(feeds, Feed{
+					Title:    title,
+					URL:      url,
+					Category: joinFolders(folders),
+				})
+				continue
+			}
+
+			// An outline without an xmlUrl is a folder
+			walk(outline.Outlines, append(folders[:len(folders):len(folders)], title))
+		}
+	}
+	walk(doc.Body.Outlines, nil)
+
+	return feeds, nil
+}
+
+// Write writes feeds as an OPML 2.0 document, grouping them into folders
+// by category.
+func Write(w io.Writer, title string, feeds []Feed) error {
+	doc := Document{
+		Version: "2.0",
+		Head: Head{
+			Title:       title,
+			DateCreated: time.Now().UTC().Format(time.RFC1123Z),
+		},
+	}
+
+	for _, feed := range feeds {
+		outline := Outline{
+			Text:   feed.Title,
+			Title:  feed.Title,
+			Type:   "rss",
+			XMLURL: feed.URL,
+		}
+
+		// Find or create the nested folders for the category
+		outlines := &doc.Body.Outlines
+		if feed.Category != "" {
+			for _, folder := range splitFolders(feed.Category) {
+				outlines = folderOutlines(outlines, folder)
+			}
+		}
+		*outlines = append(*outlines, outline)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return fmt.Errorf("failed to write OPML: %w", err)
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(doc); err != nil {
+		return fmt.Errorf("failed to write OPML: %w", err)
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// folderOutlines returns the children of the named folder in outlines,
+// creating the folder if it does not exist yet.
+func folderOutlines(outlines *[]Outline, name string) *[]Outline {
+	for i := range *outlines {
+		if (*outlines)[i].XMLURL == "" && (*outlines)[i].Text == name {
+			return &(*outlines)[i].Outlines
+		}
+	}
+	*outlines = append(*outlines, Outline{Text: name, Title: name})
+	return &(*outlines)[len(*outlines)-1].Outlines
+}
+
+// folderEscaper escapes the separator and the escape character in folder
+// names, so that a category splits back into exactly the folders it was
+// joined from.
+var folderEscaper = strings.NewReplacer(`\`, `\\`, "/", `\/`)
+
+// joinFolders joins a folder path into a category.
+func joinFolders(folders []string) string {
+	escaped := make([]string, len(folders))
+	for i, folder := range folders {
+		escaped[i] = folderEscaper.Replace(folder)
+	}
+	return strings.Join(escaped, "/")
+}
+
+// splitFolders splits a category into its folder path, undoing the escaping
+// of joinFolders.
+func splitFolders(category string) []string {
+	var folders []string
+	var folder strings.Builder
+	for i := 0; i < len(category); i++ {
+		switch c := category[i]; {
+		case c == '\\' && i+1 < len(category):
+			i++
+			folder.WriteByte(category[i])
+		case c == '/':
+			folders = append(folders, folder.String())
+			folder.Reset()
+		default:
+			folder.WriteByte(c)
+		}
+	}
+	return append(folders, folder.String())
+}
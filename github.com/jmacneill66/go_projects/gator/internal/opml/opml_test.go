@@ -0,0 +1,91 @@
+package opml
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	const doc = `<?xml version="1.0" encoding="UTF-8"?>
+<opml version="1.0">
+<head><title>Subscriptions</title></head>
+<body>
+	<outline text="Loose" xmlUrl=" https://example.com/loose.xml " htmlUrl="https://example.com/"/>
+	<outline text="Tech">
+		<outline text="Go" title="The Go Blog" type="rss" xmlUrl="https://go.dev/blog/feed.atom"/>
+		<outline text="Deep">
+			<outline text="Nested" xmlUrl="https://example.com/nested.xml"/>
+		</outline>
+	</outline>
+	<outline text="News/Politics">
+		<outline text="Slashed" xmlUrl="https://example.com/slashed.xml"/>
+	</outline>
+</body>
+</opml>`
+	feeds, err := Parse(strings.NewReader(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Feed{
+		{Title: "Loose", URL: "https://example.com/loose.xml"},
+		{Title: "The Go Blog", URL: "https://go.dev/blog/feed.atom", Category: "Tech"},
+		{Title: "Nested", URL: "https://example.com/nested.xml", Category: "Tech/Deep"},
+		{Title: "Slashed", URL: "https://example.com/slashed.xml", Category: `News\/Politics`},
+	}
+	if !reflect.DeepEqual(feeds, want) {
+		t.Errorf("got %+v, want %+v", feeds, want)
+	}
+
+	if _, err := Parse(strings.NewReader("<opml><body>")); err == nil {
+		t.Error("got no error for a truncated document")
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	feeds := []Feed{
+		{Title: "Loose", URL: "https://example.com/loose.xml"},
+		{Title: "Go", URL: "https://go.dev/blog/feed.atom", Category: "Tech"},
+		{Title: "Rust", URL: "https://blog.rust-lang.org/feed.xml", Category: "Tech"},
+		{Title: "Nested", URL: "https://example.com/nested.xml", Category: "Tech/Deep"},
+		{Title: "Slashed", URL: "https://example.com/slashed.xml", Category: joinFolders([]string{"A/B folder"})},
+		{Title: "Escaped", URL: "https://example.com/escaped.xml", Category: joinFolders([]string{`C:\feeds`, "D"})},
+	}
+	var buf bytes.Buffer
+	if err := Write(&buf, "Subscriptions", feeds); err != nil {
+		t.Fatal(err)
+	}
+	got, err := Parse(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, feeds) {
+		t.Errorf("got %+v, want %+v", got, feeds)
+	}
+}
+
+func TestSplitFolders(t *testing.T) {
+	tests := []struct {
+		category string
+		want     []string
+	}{
+		{"Tech", []string{"Tech"}},
+		{"Tech/Deep", []string{"Tech", "Deep"}},
+		{`A\/B folder`, []string{"A/B folder"}},
+		{`A\/B/C`, []string{"A/B", "C"}},
+		{`C:\\feeds/D`, []string{`C:\feeds`, "D"}},
+		{`trailing\`, []string{`trailing\`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.category, func(t *testing.T) {
+			got := splitFolders(tt.category)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("splitFolders(%q) = %q, want %q", tt.category, got, tt.want)
+			}
+			if tt.category != `trailing\` && joinFolders(got) != tt.category {
+				t.Errorf("joinFolders(%q) = %q, want %q", got, joinFolders(got), tt.category)
+			}
+		})
+	}
+}
@@ -0,0 +1,350 @@
+package render
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// DefaultWidth is the line width used when none is given.
+const DefaultWidth = 80
+
+// list tracks the state of an open <ul> or <ol>.
+type list struct {
+	ordered bool
+	next    int
+}
+
+// renderer converts an HTML tree into wrapped terminal text.
+type renderer struct {
+	base  *url.URL
+	width int
+
+	out    strings.Builder
+	inline strings.Builder // text of the current paragraph
+	links  []string        // footnote URLs, numbered from 1
+
+	indent []string // prefixes for nested lists and quotes
+	lists  []list
+	marker string // list marker for the next flushed line
+	pre    int    // depth of <pre> elements
+	gap    bool   // whether the next paragraph starts after a blank line
+}
+
+// Text renders post HTML as readable terminal text: paragraphs are wrapped
+// to width, lists get bullets or numbers, links become numbered footnotes
+// and images are replaced by their alt text. Relative links are resolved
+// against baseURL, and scripts and styles are dropped.
+func Text(body, baseURL string, width int) string {
+	if width <= 0 {
+		width = DefaultWidth
+	}
+
+	doc, err := html.Parse(strings.NewReader(body))
+	if err != nil {
+		// Not HTML we can parse; show it as it is
+		return strings.TrimSpace(body)
+	}
+
+	r := &renderer{width: width}
+	if baseURL != "" {
+		if u, err := url.Parse(baseURL); err == nil {
+			r.base = u
+		}
+	}
+
+	r.walk(doc)
+	r.flush()
+
+	if len(r.links) > 0 {
+		r.out.WriteString("\n")
+		for i, link := range r.links {
+			fmt.Fprintf(&r.out, "[%d] %s\n", i+1, link)
+		}
+	}
+	return strings.TrimRight(r.out.String(), "\n")
+}
+
+// walk renders a node and its children.
+func (r *renderer) walk(n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		r.text(n.Data)
+		return
+	case html.ElementNode:
+		// handled below
+	default:
+		r.children(n)
+		return
+	}
+
+	switch n.DataAtom {
+	case atom.Script, atom.Style, atom.Noscript, atom.Template, atom.Iframe, atom.Head, atom.Svg:
+		return
+
+	case atom.P, atom.Div, atom.Section, atom.Article, atom.Header, atom.Footer,
+		atom.Main, atom.Nav, atom.Aside, atom.Address, atom.Details, atom.Summary,
+		atom.Figure, atom.Figcaption, atom.Table, atom.Caption, atom.Tr, atom.Dl, atom.Dt, atom.Dd:
+		r.flush()
+		r.children(n)
+		r.flush()
+
+	case atom.Td, atom.Th:
+		// Each row is a paragraph, with its cells separated by bars
+		if hasPrevCell(n) {
+			if !r.endsWithSpace() {
+				r.inline.WriteString(" ")
+			}
+			r.inline.WriteString("| ")
+		}
+		r.children(n)
+
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+		r.flush()
+		level := int(n.Data[1] - '0')
+		r.inline.WriteString(strings.Repeat("#", level) + " ")
+		r.children(n)
+		r.flush()
+
+	case atom.Br:
+		r.inline.WriteString("\n")
+
+	case atom.Hr:
+		r.flush()
+		r.inline.WriteString(strings.Repeat("─", min(r.width, 40)))
+		r.flush()
+
+	case atom.Em, atom.I, atom.Cite:
+		r.wrapInline(n, "_")
+	case atom.Strong, atom.B:
+		r.wrapInline(n, "*")
+	case atom.Code, atom.Kbd, atom.Samp:
+		if r.pre > 0 {
+			r.children(n)
+		} else {
+			r.wrapInline(n, "`")
+		}
+
+	case atom.A:
+		r.children(n)
+		if link := r.resolve(attr(n, "href")); link != "" {
+			r.links = append(r.links, link)
+			fmt.Fprintf(&r.inline, "[%d]", len(r.links))
+		}
+
+	case atom.Img:
+		if alt := strings.TrimSpace(attr(n, "alt")); alt != "" {
+			fmt.Fprintf(&r.inline, "[image: %s]", alt)
+		} else {
+			r.inline.WriteString("[image]")
+		}
+
+	case atom.Ul, atom.Ol:
+		// Nested lists continue their parent item without a blank line
+		nested := len(r.lists) > 0
+		if nested {
+			r.writeParagraph()
+		} else {
+			r.flush()
+		}
+		r.lists = append(r.lists, list{ordered: n.DataAtom == atom.Ol, next: 1})
+		r.children(n)
+		r.lists = r.lists[:len(r.lists)-1]
+		r.writeParagraph()
+		if !nested {
+			r.gap = true
+		}
+
+	case atom.Li:
+		r.writeParagraph()
+		marker := "• "
+		if len(r.lists) > 0 {
+			current := &r.lists[len(r.lists)-1]
+			if current.ordered {
+				marker = fmt.Sprintf("%d. ", current.next)
+				current.next++
+			}
+		}
+		r.marker = marker
+		r.indent = append(r.indent, strings.Repeat(" ", utf8.RuneCountInString(marker)))
+		r.children(n)
+		r.writeParagraph()
+		r.indent = r.indent[:len(r.indent)-1]
+
+	case atom.Blockquote:
+		r.flush()
+		r.indent = append(r.indent, "> ")
+		r.children(n)
+		r.flush()
+		r.indent = r.indent[:len(r.indent)-1]
+
+	case atom.Pre:
+		r.flush()
+		r.pre++
+		r.indent = append(r.indent, "    ")
+		r.children(n)
+		r.flush()
+		r.indent = r.indent[:len(r.indent)-1]
+		r.pre--
+
+	default:
+		r.children(n)
+	}
+}
+
+// hasPrevCell reports whether a table cell follows another in its row.
+func hasPrevCell(n *html.Node) bool {
+	for sib := n.PrevSibling; sib != nil; sib = sib.PrevSibling {
+		if sib.Type == html.ElementNode && (sib.DataAtom == atom.Td || sib.DataAtom == atom.Th) {
+			return true
+		}
+	}
+	return false
+}
+
+// children renders the children of a node.
+func (r *renderer) children(n *html.Node) {
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		r.walk(child)
+	}
+}
+
+// wrapInline renders a node's children between emphasis markers.
+func (r *renderer) wrapInline(n *html.Node, mark string) {
+	r.inline.WriteString(mark)
+	r.children(n)
+	r.inline.WriteString(mark)
+}
+
+// text adds text to the current paragraph, collapsing whitespace outside <pre>.
+func (r *renderer) text(s string) {
+	if r.pre > 0 {
+		r.inline.WriteString(s)
+		return
+	}
+	if s == "" {
+		return
+	}
+
+	collapsed := strings.Join(strings.Fields(s), " ")
+	if isSpace(s[0]) && !r.endsWithSpace() {
+		r.inline.WriteString(" ")
+	}
+	r.inline.WriteString(collapsed)
+	if collapsed != "" && isSpace(s[len(s)-1]) {
+		r.inline.WriteString(" ")
+	}
+}
+
+// endsWithSpace reports whether the current paragraph is empty or ends in whitespace.
+func (r *renderer) endsWithSpace() bool {
+	s := r.inline.String()
+	return s == "" || isSpace(s[len(s)-1])
+}
+
+// flush ends the current block: the paragraph is written and whatever
+// comes next is separated by a blank line.
+func (r *renderer) flush() {
+	r.writeParagraph()
+	r.gap = true
+}
+
+// writeParagraph wraps and writes the current paragraph, reporting whether
+// anything was written.
+func (r *renderer) writeParagraph() bool {
+	text := r.inline.String()
+	r.inline.Reset()
+
+	prefix := strings.Join(r.indent, "")
+	first := prefix
+	if r.marker != "" && len(r.indent) > 0 {
+		// The list marker replaces the item's own indentation on its first line
+		first = strings.Join(r.indent[:len(r.indent)-1], "") + r.marker
+	}
+
+	var lines []string
+	if r.pre > 0 {
+		lines = strings.Split(strings.Trim(text, "\n"), "\n")
+	} else {
+		for _, line := range strings.Split(text, "\n") {
+			lines = append(lines, wrap(strings.TrimSpace(line), r.width-utf8.RuneCountInString(prefix))...)
+		}
+	}
+	if strings.TrimSpace(strings.Join(lines, "")) == "" {
+		return false
+	}
+	if r.gap && r.out.Len() > 0 {
+		r.out.WriteString("\n")
+	}
+	r.gap = false
+	r.marker = ""
+
+	for i, line := range lines {
+		if i == 0 {
+			r.out.WriteString(strings.TrimRight(first+line, " ") + "\n")
+		} else {
+			r.out.WriteString(strings.TrimRight(prefix+line, " ") + "\n")
+		}
+	}
+	return true
+}
+
+// resolve returns the absolute URL for a link, or "" for links that are
+// not worth a footnote.
+func (r *renderer) resolve(href string) string {
+	href = strings.TrimSpace(href)
+	if href == "" || strings.HasPrefix(href, "#") || strings.HasPrefix(strings.ToLower(href), "javascript:") {
+		return ""
+	}
+	u, err := url.Parse(href)
+	if err != nil {
+		return ""
+	}
+	if r.base != nil {
+		u = r.base.ResolveReference(u)
+	}
+	return u.String()
+}
+
+// wrap splits text into lines of at most width runes, breaking at spaces.
+// Words longer than width get a line of their own.
+func wrap(text string, width int) []string {
+	if width < 20 {
+		width = 20
+	}
+	words := strings.Fields(text)
+	if len(words) == 0 {
+		return []string{""}
+	}
+
+	var lines []string
+	line := words[0]
+	for _, word := range words[1:] {
+		if utf8.RuneCountInString(line)+1+utf8.RuneCountInString(word) > width {
+			lines = append(lines, line)
+			line = word
+			continue
+		}
+		line += " " + word
+	}
+	return append(lines, line)
+}
+
+// attr returns the value of an HTML attribute, or "" if it is not set.
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+// isSpace reports whether b is an ASCII whitespace character.
+func isSpace(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\n' || b == '\r' || b == '\f'
+}
@@ -0,0 +1,49 @@
+package render
+
+import "testing"
+
+func TestText(t *testing.T) {
+	tests := []struct {
+		name  string
+		body  string
+		width int
+		want  string
+	}{
+		{"plain text", "Just text", 0, "Just text"},
+		{"paragraphs", "<p>One</p><p>Two</p>", 0, "One\n\nTwo"},
+		{"whitespace", "<p>  Lots\n\tof   space </p>", 0, "Lots of space"},
+		{"emphasis", "<p><em>soft</em>, <strong>loud</strong> and <code>x := 1</code></p>", 0, "_soft_, *loud* and `x := 1`"},
+		{"line break", "<p>One<br>Two</p>", 0, "One\nTwo"},
+		{"heading", "<h2>Title</h2><p>Body</p>", 0, "## Title\n\nBody"},
+		{
+			"wrapping",
+			"<p>the quick brown fox jumps over the lazy dog again and again</p>",
+			20,
+			"the quick brown fox\njumps over the lazy\ndog again and again",
+		},
+		{"bullets", "<ul><li>One</li><li>Two</li></ul>", 0, "• One\n• Two"},
+		{"numbers", "<ol><li>One</li><li>Two</li></ol>", 0, "1. One\n2. Two"},
+		{"nested list", "<ul><li>One<ul><li>Inner</li></ul></li><li>Two</li></ul>", 0, "• One\n  • Inner\n• Two"},
+		{"quote", "<blockquote><p>Quoted</p></blockquote>", 0, "> Quoted"},
+		{"preformatted", "<pre><code>if x {\n  y()\n}</code></pre>", 0, "    if x {\n      y()\n    }"},
+		{"table", "<table><tr><th>A</th><th>B</th></tr><tr><td>1</td><td>2</td></tr></table>", 0, "A | B\n\n1 | 2"},
+		{
+			"links",
+			`<p>See <a href="/about">this</a> and <a href="https://other.example.com/">that</a>.</p>`,
+			0,
+			"See this[1] and that[2].\n\n[1] https://example.com/about\n[2] https://other.example.com/",
+		},
+		{"skipped links", `<p><a href="#top">Top</a> <a href="javascript:alert(1)">Run</a></p>`, 0, "Top Run"},
+		{"images", `<p><img src="a.png" alt="A cat"> <img src="b.png"></p>`, 0, "[image: A cat] [image]"},
+		{"scripts and styles", "<style>p{}</style><p>Safe</p><script>alert(1)</script>", 0, "Safe"},
+		{"entities", "<p>Fish &amp; chips &lt;3</p>", 0, "Fish & chips <3"},
+		{"empty", "", 0, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Text(tt.body, "https://example.com/posts/1", tt.width); got != tt.want {
+				t.Errorf("Text(%q) =\n%s\nwant\n%s", tt.body, got, tt.want)
+			}
+		})
+	}
+}
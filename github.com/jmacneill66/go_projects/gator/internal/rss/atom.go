@@ -0,0 +1,116 @@
+package rss
+
+import "strings"
+
+// atomFeed holds the feed-level elements of an Atom 1.0 document.
+// Entries are converted to items as they are read.
+type atomFeed struct {
+	Title     string
+	Subtitle  string
+	Links     []atomLink
+	Logo      string
+	Icon      string
+	Generator string
+}
+
+// atomEntry represents an individual <entry> in an Atom feed.
+type atomEntry struct {
+	ID        string     `xml:"id"`
+	Title     string     `xml:"title"`
+	Author    atomPerson `xml:"author"`
+	Links     []atomLink `xml:"link"`
+	Summary   atomText   `xml:"summary"`
+	Content   atomText   `xml:"content"`
+	Published string     `xml:"published"`
+	Updated   string     `xml:"updated"`
+}
+
+// atomLink represents an Atom <link> element.
+type atomLink struct {
+	Href   string `xml:"href,attr"`
+	Rel    string `xml:"rel,attr"`
+	Type   string `xml:"type,attr"`
+	Length string `xml:"length,attr"`
+}
+
+// atomPerson represents an Atom person construct such as <author>.
+type atomPerson struct {
+	Name string `xml:"name"`
+}
+
+// atomText represents an Atom text construct such as <summary> or <content>.
+type atomText struct {
+	Type  string `xml:"type,attr"`
+	Text  string `xml:",chardata"`
+	Inner string `xml:",innerxml"`
+}
+
+// String returns the text, keeping the markup of type="xhtml" content.
+func (t atomText) String() string {
+	if t.Type == "xhtml" {
+		return strings.TrimSpace(t.Inner)
+	}
+	return strings.TrimSpace(t.Text)
+}
+
+// alternateLink returns the href of the rel="alternate" link.
+// A link without a rel attribute is treated as alternate, as per RFC 4287.
+func alternateLink(links []atomLink) string {
+	for _, link := range links {
+		if link.Rel == "" || link.Rel == "alternate" {
+			return strings.TrimSpace(link.Href)
+		}
+	}
+	return ""
+}
+
+// relLink returns the href of the first link with the given rel.
+func relLink(links []atomLink, rel string) string {
+	for _, link := range links {
+		if link.Rel == rel {
+			return strings.TrimSpace(link.Href)
+		}
+	}
+	return ""
+}
+
+// enclosures returns the rel="enclosure" links as enclosures.
+func enclosures(links []atomLink) []Enclosure {
+	var result []Enclosure
+	for _, link := range links {
+		if link.Rel == "enclosure" && link.Href != "" {
+			result = append(result, Enclosure{
+				URL:    strings.TrimSpace(link.Href),
+				Length: link.Length,
+				Type:   link.Type,
+			})
+		}
+	}
+	return result
+}
+
+// toItem maps an Atom entry onto the common RSSItem model.
+func (e *atomEntry) toItem() RSSItem {
+	// Prefer the summary as the description, falling back to the full content
+	description := e.Summary.String()
+	if description == "" {
+		description = e.Content.String()
+	}
+
+	// Prefer the original publish date over the last update
+	pubDate := e.Published
+	if pubDate == "" {
+		pubDate = e.Updated
+	}
+
+	return RSSItem{
+		GUID:        strings.TrimSpace(e.ID),
+		Title:       e.Title,
+		Link:        alternateLink(e.Links),
+		Description: description,
+		Content:     e.Content.String(),
+		PubDate:     strings.TrimSpace(pubDate),
+		Creator:     strings.TrimSpace(e.Author.Name),
+		Enclosures:  enclosures(e.Links),
+	}
+}
@@ -0,0 +1,65 @@
+package rss
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseAtom(t *testing.T) {
+	const body = `<?xml version="1.0" encoding="utf-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom" xml:lang="en">
+	<title>Example Blog</title>
+	<subtitle>Notes &amp; links</subtitle>
+	<link rel="self" href="https://example.com/atom.xml"/>
+	<link href="https://example.com/"/>
+	<entry>
+		<id>urn:uuid:1</id>
+		<title>First</title>
+		<link rel="alternate" href="https://example.com/1"/>
+		<summary>Short</summary>
+		<content type="html">&lt;p&gt;Long&lt;/p&gt;</content>
+		<published>2006-01-02T15:04:05Z</published>
+		<updated>2006-01-03T15:04:05Z</updated>
+	</entry>
+	<entry>
+		<id>urn:uuid:2</id>
+		<title>Second</title>
+		<link href="https://example.com/2"/>
+		<content type="xhtml"><div xmlns="http://www.w3.org/1999/xhtml"><p>Body</p></div></content>
+		<updated>2006-01-04T15:04:05Z</updated>
+	</entry>
+</feed>`
+	feed, err := parseFeed(strings.NewReader(body), "application/atom+xml", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	channel := feed.Channel
+	if channel.Title != "Example Blog" || channel.Description != "Notes & links" ||
+		channel.Link != "https://example.com/" || channel.Language != "en" {
+		t.Errorf("got channel %q %q %q %q", channel.Title, channel.Description, channel.Link, channel.Language)
+	}
+
+	if len(channel.Item) != 2 {
+		t.Fatalf("got %d items, want 2", len(channel.Item))
+	}
+	first, second := channel.Item[0], channel.Item[1]
+	if first.GUID != "urn:uuid:1" || first.Title != "First" || first.Link != "https://example.com/1" {
+		t.Errorf("first entry: got %q %q %q", first.GUID, first.Title, first.Link)
+	}
+	if first.Description != "Short" || first.Content != "<p>Long</p>" {
+		t.Errorf("first entry: got description %q and content %q", first.Description, first.Content)
+	}
+	if first.PubDate != "2006-01-02T15:04:05Z" {
+		t.Errorf("first entry: got date %q, want the published date", first.PubDate)
+	}
+
+	// Without a summary the content is the description, and without a
+	// published date the update date is used
+	if second.Link != "https://example.com/2" || second.PubDate != "2006-01-04T15:04:05Z" {
+		t.Errorf("second entry: got link %q and date %q", second.Link, second.PubDate)
+	}
+	if !strings.Contains(second.Description, "<p>Body</p>") || second.Description != second.Content {
+		t.Errorf("second entry: got description %q and content %q", second.Description, second.Content)
+	}
+}
@@ -0,0 +1,91 @@
+package rss
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"mime"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/html/charset"
+)
+
+// utf8BOM is the UTF-8 byte order mark, which encoding/xml does not accept.
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// newXMLDecoder returns a decoder that transcodes a feed body to UTF-8.
+// The charset parameter of the Content-Type header takes precedence, then a
+// byte order mark, then the encoding in the XML declaration. Servers often
+// claim UTF-8 for every response, so a header charset of UTF-8 is ignored
+// when the body is not valid UTF-8.
+func newXMLDecoder(r *bufio.Reader, contentType string) (*xml.Decoder, error) {
+	if head, _ := r.Peek(len(utf8BOM)); bytes.Equal(head, utf8BOM) {
+		r.Discard(len(utf8BOM))
+	}
+
+	label := ""
+	if _, params, err := mime.ParseMediaType(contentType); err == nil {
+		label = params["charset"]
+	}
+	if isUTF8Label(label) && !looksUTF8(r) {
+		label = ""
+	}
+	if label == "" {
+		head, _ := r.Peek(512)
+		if _, name, certain := charset.DetermineEncoding(head, "text/xml"); certain {
+			label = name
+		}
+	}
+
+	// Let the XML declaration pick the charset
+	if label == "" {
+		decoder := xml.NewDecoder(r)
+		decoder.CharsetReader = charset.NewReaderLabel
+		return decoder, nil
+	}
+
+	var input io.Reader = r
+	if !isUTF8Label(label) {
+		encoding, name := charset.Lookup(label)
+		if encoding == nil {
+			return nil, fmt.Errorf("unsupported charset: %s", label)
+		}
+		if name != "utf-8" {
+			input = encoding.NewDecoder().Reader(r)
+		}
+	}
+
+	// The body is UTF-8 from here on, whatever the XML declaration says
+	decoder := xml.NewDecoder(input)
+	decoder.CharsetReader = utf8CharsetReader
+	return decoder, nil
+}
+
+// utf8CharsetReader ignores the encoding in the XML declaration once the
+// body has already been transcoded to UTF-8.
+func utf8CharsetReader(label string, input io.Reader) (io.Reader, error) {
+	return input, nil
+}
+
+// isUTF8Label reports whether a charset label names UTF-8.
+func isUTF8Label(label string) bool {
+	return strings.EqualFold(label, "utf-8") || strings.EqualFold(label, "utf8")
+}
+
+// looksUTF8 reports whether the buffered start of the body is valid UTF-8,
+// ignoring a character cut off at the end of the buffer.
+func looksUTF8(r *bufio.Reader) bool {
+	head, _ := r.Peek(r.Size())
+	for i := len(head) - 1; i >= 0 && i >= len(head)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(head[i]) {
+			if !utf8.FullRune(head[i:]) {
+				head = head[:i]
+			}
+			break
+		}
+	}
+	return utf8.Valid(head)
+}
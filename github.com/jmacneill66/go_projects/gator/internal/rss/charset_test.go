@@ -0,0 +1,83 @@
+package rss
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseCharset(t *testing.T) {
+	// rssWith returns an RSS document with the given XML declaration and
+	// item title, written in raw bytes
+	rssWith := func(declaration, title string) string {
+		return declaration + `<rss version="2.0"><channel><title>Feed</title><item><title>` +
+			title + `</title></item></channel></rss>`
+	}
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		want        string
+	}{
+		{
+			"ISO-8859-1 declaration",
+			"application/rss+xml",
+			rssWith(`<?xml version="1.0" encoding="ISO-8859-1"?>`, "Caf\xe9 cr\xe8me"),
+			"Café crème",
+		},
+		{
+			"windows-1252 declaration",
+			"text/xml",
+			rssWith(`<?xml version="1.0" encoding="windows-1252"?>`, "\x93Quoted\x94 \x80 5"),
+			"“Quoted” € 5",
+		},
+		{
+			"Shift_JIS declaration",
+			"application/xml",
+			rssWith(`<?xml version="1.0" encoding="Shift_JIS"?>`, "\x93\xfa\x96{"),
+			"日本",
+		},
+		{
+			"header charset over the declaration",
+			"application/rss+xml; charset=ISO-8859-1",
+			rssWith(`<?xml version="1.0" encoding="UTF-8"?>`, "Caf\xe9"),
+			"Café",
+		},
+		{
+			"header claiming UTF-8 for Latin-1",
+			"application/rss+xml; charset=utf-8",
+			rssWith(`<?xml version="1.0" encoding="ISO-8859-1"?>`, "Caf\xe9"),
+			"Café",
+		},
+		{
+			"UTF-8 with a byte order mark",
+			"application/rss+xml; charset=utf-8",
+			"\xEF\xBB\xBF" + rssWith(`<?xml version="1.0" encoding="UTF-8"?>`, "Café"),
+			"Café",
+		},
+		{
+			"no declaration",
+			"application/rss+xml",
+			rssWith("", "Café"),
+			"Café",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			feed, err := parseFeed(strings.NewReader(tt.body), tt.contentType, 10)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(feed.Channel.Item) != 1 {
+				t.Fatalf("got %d items, want 1", len(feed.Channel.Item))
+			}
+			if got := feed.Channel.Item[0].Title; got != tt.want {
+				t.Errorf("got title %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	body := rssWith(`<?xml version="1.0"?>`, "x")
+	if _, err := parseFeed(strings.NewReader(body), "application/rss+xml; charset=x-no-such-charset", 10); err == nil {
+		t.Error("got no error for an unknown charset")
+	}
+}
@@ -0,0 +1,206 @@
+package rss
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/jmacneill66/go_projects/gator/internal/config"
+)
+
+// Default client settings, used when the config leaves them unset.
+const (
+	defaultConnectTimeout  = 10 * time.Second
+	defaultReadTimeout     = 30 * time.Second
+	defaultTotalTimeout    = 60 * time.Second
+	defaultMaxRedirects    = 10
+	defaultUserAgent       = "gator"
+	defaultMaxIdleConns    = 100
+	defaultIdleConnTimeout = 90 * time.Second
+	defaultMaxBodyBytes    = 10 << 20 // 10 MiB
+	defaultMaxItems        = 5000
+)
+
+// Client fetches feeds over HTTP. It is safe for concurrent use and pools
+// connections, so a single Client should be shared by all fetches.
+type Client struct {
+	limits
+	httpClient  *http.Client
+	userAgent   string
+	readTimeout time.Duration
+}
+
+// NewClient creates a Client from the fetch settings in the config file.
+func NewClient(cfg config.FetchConfig) (*Client, error) {
+	connectTimeout := durationOr(cfg.ConnectTimeout, defaultConnectTimeout)
+	readTimeout := durationOr(cfg.ReadTimeout, defaultReadTimeout)
+
+	maxRedirects := cfg.MaxRedirects
+	if maxRedirects == 0 {
+		maxRedirects = defaultMaxRedirects
+	}
+	userAgent := cfg.UserAgent
+	if userAgent == "" {
+		userAgent = defaultUserAgent
+	}
+	maxIdleConns := cfg.MaxIdleConns
+	if maxIdleConns == 0 {
+		maxIdleConns = defaultMaxIdleConns
+	}
+
+	// Use the configured proxy, or the standard HTTP(S)_PROXY variables
+	proxy := http.ProxyFromEnvironment
+	if cfg.Proxy != "" {
+		proxyURL, err := url.Parse(cfg.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy URL: %w", err)
+		}
+		switch proxyURL.Scheme {
+		case "http", "https", "socks5", "socks5h":
+		default:
+			return nil, fmt.Errorf("unsupported proxy scheme: %s", proxyURL.Scheme)
+		}
+		proxy = http.ProxyURL(proxyURL)
+	}
+
+	// Trust a custom CA bundle in addition to the system roots
+	tlsConfig := &tls.Config{}
+	if cfg.CABundle != "" {
+		pem, err := os.ReadFile(cfg.CABundle)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA bundle: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA bundle %s", cfg.CABundle)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	dialer := &net.Dialer{
+		Timeout:   connectTimeout,
+		KeepAlive: 30 * time.Second,
+	}
+	transport := &http.Transport{
+		Proxy:                 proxy,
+		DialContext:           dialer.DialContext,
+		TLSClientConfig:       tlsConfig,
+		TLSHandshakeTimeout:   connectTimeout,
+		ResponseHeaderTimeout: readTimeout,
+		MaxIdleConns:          maxIdleConns,
+		MaxIdleConnsPerHost:   10,
+		IdleConnTimeout:       durationOr(cfg.IdleConnTimeout, defaultIdleConnTimeout),
+		ForceAttemptHTTP2:     true,
+	}
+
+	return &Client{
+		httpClient: &http.Client{
+			Transport: transport,
+			Timeout:   durationOr(cfg.TotalTimeout, defaultTotalTimeout),
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				if len(via) >= maxRedirects {
+					return fmt.Errorf("stopped after %d redirects", maxRedirects)
+				}
+				stripFeedHeaders(req, via[0])
+				return nil
+			},
+		},
+		userAgent:   userAgent,
+		readTimeout: readTimeout,
+		limits:      newLimits(cfg),
+	}, nil
+}
+
+// HTTPClient returns the underlying HTTP client, for other requests that
+// should share its proxy, timeout and TLS settings.
+func (c *Client) HTTPClient() *http.Client {
+	return c.httpClient
+}
+
+// newRequest creates a GET request with the client's User-Agent.
+func (c *Client) newRequest(ctx context.Context, rawURL string) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("User-Agent", c.userAgent)
+	return req, nil
+}
+
+// feedHeadersKey is the context key under which FetchFeed records the names
+// of a request's per-feed headers.
+type feedHeadersKey struct{}
+
+// stripFeedHeaders removes a feed's own headers, such as its credentials,
+// from a redirect to another host than the feed's. http.Client copies every
+// header to redirects, and only drops Authorization and Cookie itself.
+func stripFeedHeaders(req, original *http.Request) {
+	if strings.EqualFold(req.URL.Host, original.URL.Host) {
+		return
+	}
+	names, _ := req.Context().Value(feedHeadersKey{}).([]string)
+	for _, name := range names {
+		req.Header.Del(name)
+	}
+}
+
+// errReadTimeout cancels a fetch whose response body stalls.
+var errReadTimeout = errors.New("read timed out")
+
+// stallReader cancels a fetch when no data arrives for timeout, so a server
+// that stalls mid-response cannot hang it. Unlike a deadline on the
+// connection, it only applies while the body is being read, and leaves
+// idle pooled connections alone.
+type stallReader struct {
+	r       io.Reader
+	timer   *time.Timer
+	timeout time.Duration
+	cause   func() error
+}
+
+// newStallReader starts the timer, which calls cancel when it fires.
+func newStallReader(r io.Reader, timeout time.Duration, cancel context.CancelCauseFunc, cause func() error) *stallReader {
+	return &stallReader{
+		r:       r,
+		timer:   time.AfterFunc(timeout, func() { cancel(errReadTimeout) }),
+		timeout: timeout,
+		cause:   cause,
+	}
+}
+
+// Read reads from the body, restarting the timer whenever data arrives.
+func (s *stallReader) Read(b []byte) (int, error) {
+	n, err := s.r.Read(b)
+	if n > 0 {
+		s.timer.Reset(s.timeout)
+	}
+	if err != nil && errors.Is(s.cause(), errReadTimeout) {
+		return n, fmt.Errorf("%w after %s", errReadTimeout, s.timeout)
+	}
+	return n, err
+}
+
+// stop stops the timer once the body has been read.
+func (s *stallReader) stop() {
+	s.timer.Stop()
+}
+
+// durationOr returns d, or fallback when d is not set.
+func durationOr(d config.Duration, fallback time.Duration) time.Duration {
+	if d.Duration <= 0 {
+		return fallback
+	}
+	return d.Duration
+}
@@ -0,0 +1,141 @@
+package rss
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jmacneill66/go_projects/gator/internal/config"
+)
+
+func TestFetchFeedStall(t *testing.T) {
+	const readTimeout = 200 * time.Millisecond
+	release := make(chan struct{})
+	t.Cleanup(func() { close(release) })
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/rss+xml")
+		fmt.Fprint(w, `<rss version="2.0"><channel><title>Slow</title>`)
+		w.(http.Flusher).Flush()
+
+		// Trickle in items, taking longer than the read timeout in all but
+		// never pausing for that long, then stall if asked to
+		for i := 0; i < 4; i++ {
+			time.Sleep(readTimeout / 2)
+			fmt.Fprintf(w, "<item><guid>%d</guid></item>", i)
+			w.(http.Flusher).Flush()
+		}
+		if r.URL.Path == "/stall.xml" {
+			select {
+			case <-release:
+			case <-r.Context().Done():
+			}
+			return
+		}
+		fmt.Fprint(w, `</channel></rss>`)
+	}))
+	defer server.Close()
+
+	client, err := NewClient(config.FetchConfig{ReadTimeout: config.Duration{Duration: readTimeout}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A body that keeps arriving is read to the end
+	result, err := client.FetchFeed(context.Background(), server.URL+"/slow.xml", FetchOptions{})
+	if err != nil {
+		t.Fatalf("slow feed: %v", err)
+	}
+	if len(result.Feed.Channel.Item) != 4 {
+		t.Errorf("slow feed: got %d items, want 4", len(result.Feed.Channel.Item))
+	}
+
+	// A body that stops arriving fails after the read timeout
+	start := time.Now()
+	_, err = client.FetchFeed(context.Background(), server.URL+"/stall.xml", FetchOptions{})
+	if !errors.Is(err, errReadTimeout) {
+		t.Errorf("stalled feed: got error %v, want a read timeout", err)
+	}
+	if elapsed := time.Since(start); elapsed > 10*readTimeout {
+		t.Errorf("stalled feed took %s to time out", elapsed)
+	}
+}
+
+func TestFetchFeedRedirectHeaders(t *testing.T) {
+	const feed = `<rss version="2.0"><channel><title>Moved</title></channel></rss>`
+	// seen records the headers each server got, by host and path
+	var mu sync.Mutex
+	seen := map[string]http.Header{}
+	record := func(r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		seen[r.Host+r.URL.Path] = r.Header.Clone()
+	}
+
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		record(r)
+		w.Header().Set("Content-Type", "application/rss+xml")
+		fmt.Fprint(w, feed)
+	}))
+	defer other.Close()
+
+	var origin *httptest.Server
+	origin = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		record(r)
+		switch r.URL.Path {
+		case "/same-host":
+			http.Redirect(w, r, origin.URL+"/feed.xml", http.StatusMovedPermanently)
+		case "/other-host":
+			http.Redirect(w, r, other.URL+"/feed.xml", http.StatusMovedPermanently)
+		default:
+			w.Header().Set("Content-Type", "application/rss+xml")
+			fmt.Fprint(w, feed)
+		}
+	}))
+	defer origin.Close()
+
+	client, err := NewClient(config.FetchConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := FetchOptions{Headers: map[string]string{"X-Api-Key": "s3cret", "Authorization": "Bearer t0ken"}}
+	originHost, otherHost := origin.Listener.Addr().String(), other.Listener.Addr().String()
+
+	tests := []struct {
+		path     string
+		target   string
+		wantSent bool
+	}{
+		{"/same-host", originHost + "/feed.xml", true},
+		{"/other-host", otherHost + "/feed.xml", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			mu.Lock()
+			clear(seen)
+			mu.Unlock()
+			if _, err := client.FetchFeed(context.Background(), origin.URL+tt.path, opts); err != nil {
+				t.Fatal(err)
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if got := seen[originHost+tt.path].Get("X-Api-Key"); got != "s3cret" {
+				t.Errorf("feed's own host got X-Api-Key %q, want it sent", got)
+			}
+			header, ok := seen[tt.target]
+			if !ok {
+				t.Fatalf("redirect target %s was not fetched", tt.target)
+			}
+			for _, name := range []string{"X-Api-Key", "Authorization"} {
+				if sent := header.Get(name) != ""; sent != tt.wantSent {
+					t.Errorf("redirect target got %s: %v, want %v", name, sent, tt.wantSent)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package rss
+
+import (
+	"regexp"
+	"strings"
+	"time"
+)
+
+// dateLayouts lists the publish date formats seen in real-world feeds,
+// roughly ordered from most to least common.
+var dateLayouts = []string{
+	// RFC 822/1123 and their many variations (RSS 2.0)
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"Mon, 2 Jan 2006 15:04:05 MST",
+	"Mon, 2 Jan 2006 15:04:05 -07:00",
+	"Mon, 2 Jan 2006 15:04 -0700",
+	"Mon, 2 Jan 2006 15:04 MST",
+	"Mon, 2 Jan 2006 15:04:05",
+	"Mon, 2 Jan 06 15:04:05 -0700",
+	"Mon, 2 Jan 06 15:04:05 MST",
+	"Mon, 2 January 2006 15:04:05 -0700",
+	"Mon, 2 January 2006 15:04:05 MST",
+	"Monday, 2 January 2006 15:04:05 -0700",
+	"Monday, 2 January 2006 15:04:05 MST",
+	"2 Jan 2006 15:04:05 -0700",
+	"2 Jan 2006 15:04:05 MST",
+	"2 Jan 2006 15:04 -0700",
+	"2 Jan 2006 15:04 MST",
+	"2 Jan 06 15:04:05 -0700",
+	"2 Jan 06 15:04 -0700",
+	"2 Jan 06 15:04 MST",
+	"Mon 2 Jan 2006 15:04:05 -0700",
+	"Mon 2 Jan 2006 15:04:05 MST",
+	"Mon, 2 Jan 2006",
+	"2 Jan 2006",
+	"2 Jan 06",
+
+	// RFC 3339 / ISO 8601 (Atom, JSON Feed, Dublin Core)
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05-0700",
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05Z07:00",
+	"2006-01-02 15:04:05 -0700",
+	"2006-01-02 15:04:05 MST",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+
+	// Formats produced by assorted libraries and hand-written feeds
+	time.RFC850,
+	time.ANSIC,
+	time.UnixDate,
+	time.RubyDate,
+	"January 2, 2006 15:04:05 MST",
+	"January 2, 2006",
+	"Jan 2, 2006",
+}
+
+// zoneOffsets maps common named time zones to their UTC offset in seconds.
+// time.Parse only knows the offsets of UTC and the local zone, so any other
+// abbreviation would otherwise be silently treated as UTC.
+var zoneOffsets = map[string]int{
+	"UT": 0, "UTC": 0, "GMT": 0, "Z": 0, "WET": 0,
+	"EST": -5 * 3600, "EDT": -4 * 3600,
+	"CST": -6 * 3600, "CDT": -5 * 3600,
+	"MST": -7 * 3600, "MDT": -6 * 3600,
+	"PST": -8 * 3600, "PDT": -7 * 3600,
+	"AKST": -9 * 3600, "AKDT": -8 * 3600,
+	"HST": -10 * 3600,
+	"BST": 1 * 3600, "IST": 5*3600 + 1800,
+	"CET": 1 * 3600, "CEST": 2 * 3600, "WEST": 1 * 3600,
+	"EET": 2 * 3600, "EEST": 3 * 3600, "MSK": 3 * 3600,
+	"SGT": 8 * 3600, "HKT": 8 * 3600, "AWST": 8 * 3600,
+	"JST": 9 * 3600, "KST": 9 * 3600,
+	"ACST": 9*3600 + 1800, "ACDT": 10*3600 + 1800,
+	"AEST": 10 * 3600, "AEDT": 11 * 3600,
+	"NZST": 12 * 3600, "NZDT": 13 * 3600,
+}
+
+// dateReplacer normalizes spellings that time.Parse does not accept.
+var dateReplacer = strings.NewReplacer("Sept ", "Sep ")
+
+// dateComment matches a trailing parenthesized comment, which RFC 822
+// allows after the zone, as in "+0000 (UTC)".
+var dateComment = regexp.MustCompile(`\s*\([^()]*\)$`)
+
+// ParseDate parses a feed publish date in any of the known layouts and
+// returns it in UTC. It returns false if the date is empty or unrecognized,
+// so callers can record an unknown date instead of inventing one.
+func ParseDate(value string) (time.Time, bool) {
+	value = strings.Join(strings.Fields(value), " ")
+	if value == "" {
+		return time.Time{}, false
+	}
+	value = dateReplacer.Replace(dateComment.ReplaceAllString(value, ""))
+	if strings.HasSuffix(value, " UT") {
+		value += "C"
+	}
+
+	for _, layout := range dateLayouts {
+		// Dates without a zone are assumed to be UTC
+		t, err := time.ParseInLocation(layout, value, time.UTC)
+		if err != nil {
+			continue
+		}
+
+		// Resolve named zones that time.Parse left at a zero offset
+		if name, offset := t.Zone(); offset == 0 {
+			if known, ok := zoneOffsets[strings.ToUpper(name)]; ok {
+				t = t.Add(-time.Duration(known) * time.Second)
+			}
+		}
+		return t.UTC(), true
+	}
+
+	return time.Time{}, false
+}
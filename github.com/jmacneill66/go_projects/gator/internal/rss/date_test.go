@@ -0,0 +1,60 @@
+package rss
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDate(t *testing.T) {
+	tests := []struct {
+		value  string
+		want   time.Time
+		wantOK bool
+	}{
+		// RSS 2.0 dates
+		{"Mon, 02 Jan 2006 15:04:05 -0700", time.Date(2006, 1, 2, 22, 4, 5, 0, time.UTC), true},
+		{"Mon, 2 Jan 2006 15:04:05 GMT", time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC), true},
+		{"Mon, 2 Jan 2006 15:04:05 UT", time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC), true},
+		{"Mon, 2 Jan 2006 15:04:05 EST", time.Date(2006, 1, 2, 20, 4, 5, 0, time.UTC), true},
+		{"Mon, 2 Jan 2006 15:04:05 CEST", time.Date(2006, 1, 2, 13, 4, 5, 0, time.UTC), true},
+		{"Mon, 2 Jan 2006 15:04 +0100", time.Date(2006, 1, 2, 14, 4, 0, 0, time.UTC), true},
+		{"Mon, 02 Jan 06 15:04:05 -0700", time.Date(2006, 1, 2, 22, 4, 5, 0, time.UTC), true},
+		{"2 Jan 2006 15:04:05 +0000", time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC), true},
+		{"Sat, 2 Sept 2006 15:04:05 GMT", time.Date(2006, 9, 2, 15, 4, 5, 0, time.UTC), true},
+		{"  Mon,  2 Jan 2006\n 15:04:05 GMT ", time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC), true},
+		{"Tue, 10 Jun 2003 04:00:00 +0000 (UTC)", time.Date(2003, 6, 10, 4, 0, 0, 0, time.UTC), true},
+		{"Tue, 10 Jun 2003 04:00:00 -0400 (EDT)", time.Date(2003, 6, 10, 8, 0, 0, 0, time.UTC), true},
+		{"Tue, 10 Jun 2003", time.Date(2003, 6, 10, 0, 0, 0, 0, time.UTC), true},
+		{"10 Jun 2003", time.Date(2003, 6, 10, 0, 0, 0, 0, time.UTC), true},
+		{"10 Jun 03", time.Date(2003, 6, 10, 0, 0, 0, 0, time.UTC), true},
+
+		// Atom, JSON Feed and Dublin Core dates
+		{"2006-01-02T15:04:05Z", time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC), true},
+		{"2006-01-02T15:04:05.123+02:00", time.Date(2006, 1, 2, 13, 4, 5, 123e6, time.UTC), true},
+		{"2006-01-02T15:04:05", time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC), true},
+		{"2006-01-02 15:04:05", time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC), true},
+		{"2006-01-02", time.Date(2006, 1, 2, 0, 0, 0, 0, time.UTC), true},
+
+		// Hand-written dates
+		{"January 2, 2006", time.Date(2006, 1, 2, 0, 0, 0, 0, time.UTC), true},
+		{"Jan 2, 2006", time.Date(2006, 1, 2, 0, 0, 0, 0, time.UTC), true},
+
+		// Unknown dates
+		{"", time.Time{}, false},
+		{"   ", time.Time{}, false},
+		{"yesterday", time.Time{}, false},
+		{"2006-13-45", time.Time{}, false},
+		{"(UTC)", time.Time{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, ok := ParseDate(tt.value)
+			if ok != tt.wantOK || !got.Equal(tt.want) {
+				t.Errorf("ParseDate(%q) = %v, %v; want %v, %v", tt.value, got, ok, tt.want, tt.wantOK)
+			}
+			if ok && got.Location() != time.UTC {
+				t.Errorf("ParseDate(%q) is in %v, want UTC", tt.value, got.Location())
+			}
+		})
+	}
+}
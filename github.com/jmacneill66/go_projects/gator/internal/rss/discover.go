@@ -0,0 +1,193 @@
+package rss
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// ErrNoFeedFound is returned by Discover when a page links to no feeds.
+var ErrNoFeedFound = errors.New("no feed found")
+
+// FeedLink is a feed candidate found by Discover.
+type FeedLink struct {
+	URL   string
+	Title string
+}
+
+// feedTypes lists the MIME types of <link rel="alternate"> feed references.
+var feedTypes = map[string]bool{
+	"application/rss+xml":   true,
+	"application/atom+xml":  true,
+	"application/rdf+xml":   true,
+	"application/feed+json": true,
+}
+
+// Discoverer finds the feeds of web pages. Client discovers over HTTP and
+// FixtureFetcher from its fixtures, for tests.
+type Discoverer interface {
+	Discover(ctx context.Context, pageURL string) ([]FeedLink, error)
+}
+
+var (
+	_ Discoverer = (*Client)(nil)
+	_ Discoverer = (*FixtureFetcher)(nil)
+)
+
+// pageFetcher downloads a URL and returns its body, Content-Type and final
+// URL after redirects.
+type pageFetcher func(ctx context.Context, pageURL string) ([]byte, string, *url.URL, error)
+
+// commonFeedPaths are probed when a page does not advertise its feeds.
+var commonFeedPaths = []string{"/feed", "/rss.xml", "/index.xml", "/atom.xml", "/feed.xml", "/feed.json"}
+
+// Discover finds the feeds for pageURL. If pageURL is already a feed it is
+// returned as the only candidate. For HTML pages, the advertised
+// <link rel="alternate"> feeds are returned, falling back to probing
+// common feed paths on the same site.
+func (c *Client) Discover(ctx context.Context, pageURL string) ([]FeedLink, error) {
+	return c.discover(ctx, pageURL, c.fetchPage)
+}
+
+// discover finds the feeds for pageURL, downloading pages with fetchPage.
+func (l limits) discover(ctx context.Context, pageURL string, fetchPage pageFetcher) ([]FeedLink, error) {
+	body, contentType, finalURL, err := fetchPage(ctx, pageURL)
+	if err != nil {
+		return nil, err
+	}
+
+	// The URL already points at a feed
+	if feed, err := parseFeed(bytes.NewReader(body), contentType, l.maxItems); err == nil {
+		return []FeedLink{{URL: pageURL, Title: feed.Channel.Title}}, nil
+	}
+
+	if isHTML(contentType, body) {
+		links, err := feedLinks(body, finalURL)
+		if err != nil {
+			return nil, err
+		}
+		if len(links) > 0 {
+			return links, nil
+		}
+	}
+
+	// Probe well-known feed locations on the same site
+	var links []FeedLink
+	for _, path := range commonFeedPaths {
+		candidate := finalURL.ResolveReference(&url.URL{Path: path}).String()
+		body, contentType, _, err := fetchPage(ctx, candidate)
+		if err != nil {
+			continue
+		}
+		if feed, err := parseFeed(bytes.NewReader(body), contentType, l.maxItems); err == nil {
+			links = append(links, FeedLink{URL: candidate, Title: feed.Channel.Title})
+		}
+	}
+	if len(links) == 0 {
+		return nil, fmt.Errorf("%w at %s", ErrNoFeedFound, pageURL)
+	}
+	return links, nil
+}
+
+// fetchPage downloads a URL and returns its body, Content-Type and final URL
+// after redirects.
+func (c *Client) fetchPage(ctx context.Context, pageURL string) ([]byte, string, *url.URL, error) {
+	req, err := c.newRequest(ctx, pageURL)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("failed to fetch %s: %w", pageURL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, "", nil, fmt.Errorf("unexpected status fetching %s: %s", pageURL, resp.Status)
+	}
+
+	body, err := io.ReadAll(newCappedReader(resp.Body, c.maxBodyBytes))
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	return body, resp.Header.Get("Content-Type"), resp.Request.URL, nil
+}
+
+// isHTML reports whether a response is an HTML page.
+func isHTML(contentType string, body []byte) bool {
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil {
+		return mediaType == "text/html" || mediaType == "application/xhtml+xml"
+	}
+	return strings.HasPrefix(http.DetectContentType(body), "text/html")
+}
+
+// feedLinks extracts the <link rel="alternate"> feeds from an HTML page,
+// resolving them against the page's <base> or its own URL.
+func feedLinks(body []byte, pageURL *url.URL) ([]FeedLink, error) {
+	doc, err := html.Parse(bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse HTML: %w", err)
+	}
+
+	base := pageURL
+	var links []FeedLink
+	seen := make(map[string]bool)
+
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			switch n.Data {
+			case "base":
+				if href := attr(n, "href"); href != "" {
+					if u, err := pageURL.Parse(href); err == nil {
+						base = u
+					}
+				}
+			case "link":
+				mediaType, _, _ := mime.ParseMediaType(attr(n, "type"))
+				href := strings.TrimSpace(attr(n, "href"))
+				if href != "" && hasToken(attr(n, "rel"), "alternate") && feedTypes[mediaType] {
+					if u, err := base.Parse(href); err == nil && !seen[u.String()] {
+						seen[u.String()] = true
+						links = append(links, FeedLink{URL: u.String(), Title: attr(n, "title")})
+					}
+				}
+			}
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+	walk(doc)
+
+	return links, nil
+}
+
+// attr returns the value of an HTML attribute, or "" if it is not set.
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if strings.EqualFold(a.Key, key) {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+// hasToken reports whether a space-separated attribute value contains token.
+func hasToken(value, token string) bool {
+	for _, field := range strings.Fields(value) {
+		if strings.EqualFold(field, token) {
+			return true
+		}
+	}
+	return false
+}
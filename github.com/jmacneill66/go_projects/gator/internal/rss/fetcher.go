@@ -0,0 +1,78 @@
+package rss
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+
+	"github.com/jmacneill66/go_projects/gator/internal/config"
+)
+
+// Fetcher fetches and parses feeds. Client fetches over HTTP, FileFetcher
+// reads file:// URLs and FixtureFetcher serves canned feeds in tests.
+// When a feed is received but cannot be parsed, FetchFeed returns the
+// result without a Feed along with the error, so the fetch can be logged.
+type Fetcher interface {
+	FetchFeed(ctx context.Context, feedURL string, opts FetchOptions) (*FetchResult, error)
+}
+
+var (
+	_ Fetcher = (*Client)(nil)
+	_ Fetcher = (*FileFetcher)(nil)
+	_ Fetcher = (*FixtureFetcher)(nil)
+	_ Fetcher = (*Router)(nil)
+)
+
+// Router is a Fetcher that sends each fetch to the Fetcher registered for
+// the scheme of the feed URL.
+type Router struct {
+	fetchers map[string]Fetcher
+}
+
+// NewRouter creates a Router with no schemes registered.
+func NewRouter() *Router {
+	return &Router{fetchers: make(map[string]Fetcher)}
+}
+
+// Handle registers the Fetcher for a URL scheme such as "https".
+func (r *Router) Handle(scheme string, fetcher Fetcher) {
+	r.fetchers[strings.ToLower(scheme)] = fetcher
+}
+
+// FetchFeed fetches the feed with the Fetcher for its URL scheme.
+func (r *Router) FetchFeed(ctx context.Context, feedURL string, opts FetchOptions) (*FetchResult, error) {
+	u, err := url.Parse(feedURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid feed URL: %w", err)
+	}
+	fetcher, ok := r.fetchers[strings.ToLower(u.Scheme)]
+	if !ok {
+		return nil, fmt.Errorf("unsupported URL scheme: %q", u.Scheme)
+	}
+	return fetcher.FetchFeed(ctx, feedURL, opts)
+}
+
+// limits caps the size of the feeds a Fetcher accepts.
+type limits struct {
+	maxBodyBytes int64
+	maxItems     int
+}
+
+// newLimits returns the limits set in the config, or the defaults.
+func newLimits(cfg config.FetchConfig) limits {
+	l := limits{maxBodyBytes: cfg.MaxBodyBytes, maxItems: cfg.MaxItems}
+	if l.maxBodyBytes == 0 {
+		l.maxBodyBytes = defaultMaxBodyBytes
+	}
+	if l.maxItems == 0 {
+		l.maxItems = defaultMaxItems
+	}
+	return l
+}
+
+// parse parses a feed body as it streams in, within the limits.
+func (l limits) parse(r io.Reader, contentType string) (*RSSFeed, error) {
+	return parseFeed(newCappedReader(r, l.maxBodyBytes), contentType, l.maxItems)
+}
@@ -0,0 +1,63 @@
+package rss
+
+import (
+	"context"
+	"fmt"
+	"mime"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/jmacneill66/go_projects/gator/internal/config"
+)
+
+// FileFetcher reads feeds from local files given as file:// URLs, such as
+// file:///home/alice/feeds/news.xml.
+type FileFetcher struct {
+	limits
+}
+
+// NewFileFetcher creates a FileFetcher with the size limits in the config.
+func NewFileFetcher(cfg config.FetchConfig) *FileFetcher {
+	return &FileFetcher{limits: newLimits(cfg)}
+}
+
+// FetchFeed reads and parses a feed file. The file's modification time is
+// used as its Last-Modified validator, so unchanged files are not parsed
+// again. It is kept to the nanosecond, as it is never sent over HTTP.
+func (f *FileFetcher) FetchFeed(ctx context.Context, feedURL string, opts FetchOptions) (*FetchResult, error) {
+	u, err := url.Parse(feedURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid feed URL: %w", err)
+	}
+	if u.Scheme != "file" || (u.Host != "" && u.Host != "localhost") {
+		return nil, fmt.Errorf("not a local file URL: %s", feedURL)
+	}
+	path := filepath.FromSlash(u.Path)
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open feed file: %w", err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read feed file: %w", err)
+	}
+	result := &FetchResult{LastModified: info.ModTime().UTC().Format(time.RFC3339Nano)}
+	if result.LastModified == opts.LastModified {
+		result.NotModified = true
+		return result, nil
+	}
+
+	body := &countingReader{r: file}
+	feed, err := f.parse(body, mime.TypeByExtension(filepath.Ext(path)))
+	result.Bytes = body.n
+	if err != nil {
+		return result, err
+	}
+	result.Feed = feed
+	return result, nil
+}
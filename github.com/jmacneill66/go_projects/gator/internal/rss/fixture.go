@@ -0,0 +1,123 @@
+package rss
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/jmacneill66/go_projects/gator/internal/config"
+)
+
+// Fixture is a canned response served by a FixtureFetcher.
+type Fixture struct {
+	// StatusCode defaults to 200 OK
+	StatusCode  int
+	ContentType string
+	Body        string
+
+	// Validators returned with the fixture. A fetch that sends the same
+	// ETag or Last-Modified gets a 304 Not Modified result.
+	ETag         string
+	LastModified string
+
+	Redirects []Redirect
+
+	// CacheControl is the fixture's Cache-Control header
+	CacheControl string
+}
+
+// FixtureFetcher serves feeds and web pages from an in-memory map of URL to
+// Fixture, for deterministic tests of code that fetches or discovers feeds.
+type FixtureFetcher struct {
+	limits
+	mu       sync.Mutex
+	fixtures map[string]Fixture
+}
+
+// NewFixtureFetcher creates a FixtureFetcher serving the given fixtures,
+// with the default size limits.
+func NewFixtureFetcher(fixtures map[string]Fixture) *FixtureFetcher {
+	f := &FixtureFetcher{
+		limits:   newLimits(config.FetchConfig{}),
+		fixtures: make(map[string]Fixture, len(fixtures)),
+	}
+	for feedURL, fixture := range fixtures {
+		f.fixtures[feedURL] = fixture
+	}
+	return f
+}
+
+// Set adds or replaces the fixture served for a URL.
+func (f *FixtureFetcher) Set(feedURL string, fixture Fixture) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.fixtures[feedURL] = fixture
+}
+
+// FetchFeed parses the fixture for the URL as if it had been fetched.
+func (f *FixtureFetcher) FetchFeed(ctx context.Context, feedURL string, opts FetchOptions) (*FetchResult, error) {
+	f.mu.Lock()
+	fixture, ok := f.fixtures[feedURL]
+	f.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("no fixture for %s", feedURL)
+	}
+
+	status := fixture.StatusCode
+	if status == 0 {
+		status = http.StatusOK
+	}
+	if status < 200 || status > 299 {
+		return nil, &StatusError{StatusCode: status, Status: fmt.Sprintf("%d %s", status, http.StatusText(status))}
+	}
+
+	result := &FetchResult{
+		StatusCode:   status,
+		ETag:         fixture.ETag,
+		LastModified: fixture.LastModified,
+		Redirects:    fixture.Redirects,
+		MaxAge:       cacheMaxAge(http.Header{"Cache-Control": {fixture.CacheControl}}),
+	}
+	if (opts.ETag != "" && opts.ETag == fixture.ETag) ||
+		(opts.LastModified != "" && opts.LastModified == fixture.LastModified) {
+		result.StatusCode = http.StatusNotModified
+		result.NotModified = true
+		return result, nil
+	}
+
+	feed, err := f.parse(strings.NewReader(fixture.Body), fixture.ContentType)
+	result.Bytes = int64(len(fixture.Body))
+	if err != nil {
+		return result, err
+	}
+	result.Feed = feed
+	return result, nil
+}
+
+// Discover finds the feeds for pageURL among the fixtures, as Client.Discover
+// would over HTTP.
+func (f *FixtureFetcher) Discover(ctx context.Context, pageURL string) ([]FeedLink, error) {
+	return f.discover(ctx, pageURL, f.fetchPage)
+}
+
+// fetchPage returns the body, Content-Type and URL of the fixture for a URL.
+func (f *FixtureFetcher) fetchPage(ctx context.Context, pageURL string) ([]byte, string, *url.URL, error) {
+	f.mu.Lock()
+	fixture, ok := f.fixtures[pageURL]
+	f.mu.Unlock()
+	if !ok {
+		return nil, "", nil, fmt.Errorf("no fixture for %s", pageURL)
+	}
+	if fixture.StatusCode != 0 && (fixture.StatusCode < 200 || fixture.StatusCode > 299) {
+		return nil, "", nil, fmt.Errorf("unexpected status fetching %s: %d", pageURL, fixture.StatusCode)
+	}
+
+	u, err := url.Parse(pageURL)
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("invalid URL: %w", err)
+	}
+	return []byte(fixture.Body), fixture.ContentType, u, nil
+}
@@ -0,0 +1,62 @@
+package rss
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// syndicationPeriods maps sy:updatePeriod values to their length.
+var syndicationPeriods = map[string]time.Duration{
+	"hourly":  time.Hour,
+	"daily":   24 * time.Hour,
+	"weekly":  7 * 24 * time.Hour,
+	"monthly": 30 * 24 * time.Hour,
+	"yearly":  365 * 24 * time.Hour,
+}
+
+// UpdateInterval returns how often the publisher asks for the feed to be
+// polled: the larger of its <ttl> and its sy:updatePeriod divided by
+// sy:updateFrequency. It returns 0 when the feed gives neither.
+func (f *RSSFeed) UpdateInterval() time.Duration {
+	var interval time.Duration
+	if minutes, err := strconv.Atoi(strings.TrimSpace(f.Channel.TTL)); err == nil && minutes > 0 {
+		interval = time.Duration(minutes) * time.Minute
+	}
+
+	// The syndication module defaults to once a day when only one of its
+	// elements is present
+	period := strings.ToLower(strings.TrimSpace(f.Channel.UpdatePeriod))
+	frequency := strings.TrimSpace(f.Channel.UpdateFrequency)
+	if period == "" && frequency == "" {
+		return interval
+	}
+	length, ok := syndicationPeriods[period]
+	if !ok {
+		length = syndicationPeriods["daily"]
+	}
+	times, err := strconv.Atoi(frequency)
+	if err != nil || times < 1 {
+		times = 1
+	}
+	return max(interval, length/time.Duration(times))
+}
+
+// cacheMaxAge returns the max-age directive of a Cache-Control header, or 0
+// when there is none or the response must not be cached.
+func cacheMaxAge(header http.Header) time.Duration {
+	var maxAge time.Duration
+	for _, directive := range strings.Split(header.Get("Cache-Control"), ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(directive), "=")
+		switch strings.ToLower(name) {
+		case "no-store":
+			return 0
+		case "max-age":
+			if seconds, err := strconv.Atoi(strings.Trim(value, `"`)); err == nil && seconds > 0 {
+				maxAge = time.Duration(seconds) * time.Second
+			}
+		}
+	}
+	return maxAge
+}
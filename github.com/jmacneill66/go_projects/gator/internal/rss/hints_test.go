@@ -0,0 +1,65 @@
+package rss
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestUpdateInterval(t *testing.T) {
+	tests := []struct {
+		name                 string
+		ttl, period, updates string
+		want                 time.Duration
+	}{
+		{"none", "", "", "", 0},
+		{"ttl", "60", "", "", time.Hour},
+		{"ttl with spaces", " 30 ", "", "", 30 * time.Minute},
+		{"invalid ttl", "soon", "", "", 0},
+		{"negative ttl", "-5", "", "", 0},
+		{"hourly", "", "hourly", "", time.Hour},
+		{"twice daily", "", "daily", "2", 12 * time.Hour},
+		{"weekly", "", "Weekly", "1", 7 * 24 * time.Hour},
+		{"frequency defaults to daily", "", "", "4", 6 * time.Hour},
+		{"unknown period is daily", "", "fortnightly", "", 24 * time.Hour},
+		{"invalid frequency is once", "", "hourly", "0", time.Hour},
+		{"larger ttl wins", "120", "hourly", "1", 2 * time.Hour},
+		{"larger period wins", "10", "daily", "1", 24 * time.Hour},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			feed := &RSSFeed{}
+			feed.Channel.TTL = tt.ttl
+			feed.Channel.UpdatePeriod = tt.period
+			feed.Channel.UpdateFrequency = tt.updates
+			if got := feed.UpdateInterval(); got != tt.want {
+				t.Errorf("UpdateInterval() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCacheMaxAge(t *testing.T) {
+	tests := []struct {
+		cacheControl string
+		want         time.Duration
+	}{
+		{"", 0},
+		{"max-age=300", 5 * time.Minute},
+		{"public, max-age=3600", time.Hour},
+		{"Max-Age=60, must-revalidate", time.Minute},
+		{`max-age="120"`, 2 * time.Minute},
+		{"max-age=0", 0},
+		{"max-age=soon", 0},
+		{"no-cache", 0},
+		{"max-age=300, no-store", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.cacheControl, func(t *testing.T) {
+			header := http.Header{"Cache-Control": {tt.cacheControl}}
+			if got := cacheMaxAge(header); got != tt.want {
+				t.Errorf("cacheMaxAge(%q) = %s, want %s", tt.cacheControl, got, tt.want)
+			}
+		})
+	}
+}
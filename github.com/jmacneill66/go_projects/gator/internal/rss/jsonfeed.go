@@ -0,0 +1,156 @@
+package rss
+
+import (
+	"bytes"
+	"mime"
+	"strconv"
+	"strings"
+)
+
+// jsonFeed represents a JSON Feed 1.0/1.1 document (https://jsonfeed.org).
+type jsonFeed struct {
+	Version     string         `json:"version"`
+	Title       string         `json:"title"`
+	HomePageURL string         `json:"home_page_url"`
+	FeedURL     string         `json:"feed_url"`
+	Description string         `json:"description"`
+	Icon        string         `json:"icon"`
+	Favicon     string         `json:"favicon"`
+	Language    string         `json:"language"`
+	Hubs        []jsonFeedHub  `json:"hubs"`
+	Items       []jsonFeedItem `json:"items"`
+}
+
+// jsonFeedHub represents a JSON Feed hub, such as a WebSub hub.
+type jsonFeedHub struct {
+	Type string `json:"type"`
+	URL  string `json:"url"`
+}
+
+// jsonFeedItem represents an individual item in a JSON Feed.
+type jsonFeedItem struct {
+	ID            string `json:"id"`
+	URL           string `json:"url"`
+	ExternalURL   string `json:"external_url"`
+	Title         string `json:"title"`
+	ContentHTML   string `json:"content_html"`
+	ContentText   string `json:"content_text"`
+	Summary       string `json:"summary"`
+	DatePublished string `json:"date_published"`
+	DateModified  string `json:"date_modified"`
+
+	// JSON Feed 1.0 has a single author, 1.1 replaces it with a list
+	Author  *jsonFeedAuthor  `json:"author"`
+	Authors []jsonFeedAuthor `json:"authors"`
+
+	Attachments []jsonFeedAttachment `json:"attachments"`
+}
+
+// jsonFeedAttachment represents a JSON Feed attachment such as a podcast episode.
+type jsonFeedAttachment struct {
+	URL               string  `json:"url"`
+	MimeType          string  `json:"mime_type"`
+	SizeInBytes       int64   `json:"size_in_bytes"`
+	DurationInSeconds float64 `json:"duration_in_seconds"`
+}
+
+// jsonFeedAuthor represents a JSON Feed author object.
+type jsonFeedAuthor struct {
+	Name string `json:"name"`
+}
+
+// isJSONFeed reports whether a response looks like a JSON Feed,
+// first by its Content-Type and then by sniffing the body.
+func isJSONFeed(contentType string, body []byte) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err == nil {
+		switch mediaType {
+		case "application/feed+json", "application/json":
+			return true
+		}
+	}
+	body = bytes.TrimPrefix(body, utf8BOM)
+	return bytes.HasPrefix(bytes.TrimSpace(body), []byte("{"))
+}
+
+// toRSS maps a JSON Feed onto the common RSSFeed model.
+func (j *jsonFeed) toRSS() *RSSFeed {
+	var feed RSSFeed
+	feed.Channel.Title = j.Title
+	feed.Channel.Link = j.HomePageURL
+	feed.Channel.Description = j.Description
+	feed.Channel.Language = j.Language
+	feed.Channel.Self = j.FeedURL
+	for _, hub := range j.Hubs {
+		if strings.EqualFold(hub.Type, "WebSub") {
+			feed.Channel.Hub = hub.URL
+			break
+		}
+	}
+	feed.Channel.ImageURL = j.Icon
+	if feed.Channel.ImageURL == "" {
+		feed.Channel.ImageURL = j.Favicon
+	}
+
+	for _, item := range j.Items {
+		// Prefer HTML content over plain text
+		content := item.ContentHTML
+		if content == "" {
+			content = item.ContentText
+		}
+
+		// Use the summary as the description, falling back to the content
+		description := item.Summary
+		if description == "" {
+			description = content
+		}
+
+		// Items without a url fall back to external_url, then the id
+		link := item.URL
+		if link == "" {
+			link = item.ExternalURL
+		}
+		if link == "" && strings.HasPrefix(item.ID, "http") {
+			link = item.ID
+		}
+
+		pubDate := item.DatePublished
+		if pubDate == "" {
+			pubDate = item.DateModified
+		}
+
+		var creator string
+		if len(item.Authors) > 0 {
+			creator = item.Authors[0].Name
+		} else if item.Author != nil {
+			creator = item.Author.Name
+		}
+
+		var attachments []Enclosure
+		var duration string
+		for _, attachment := range item.Attachments {
+			enclosure := Enclosure{URL: attachment.URL, Type: attachment.MimeType}
+			if attachment.SizeInBytes > 0 {
+				enclosure.Length = strconv.FormatInt(attachment.SizeInBytes, 10)
+			}
+			if attachment.DurationInSeconds > 0 && duration == "" {
+				duration = strconv.Itoa(int(attachment.DurationInSeconds))
+			}
+			attachments = append(attachments, enclosure)
+		}
+
+		feed.Channel.Item = append(feed.Channel.Item, RSSItem{
+			GUID:           item.ID,
+			Title:          item.Title,
+			Link:           link,
+			Description:    description,
+			Content:        content,
+			PubDate:        pubDate,
+			Creator:        creator,
+			Enclosures:     attachments,
+			ITunesDuration: duration,
+		})
+	}
+
+	return &feed
+}
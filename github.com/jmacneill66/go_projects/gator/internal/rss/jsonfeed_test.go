@@ -0,0 +1,94 @@
+package rss
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestIsJSONFeed(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		want        bool
+	}{
+		{"feed+json", "application/feed+json", "", true},
+		{"json with charset", "application/json; charset=utf-8", "", true},
+		{"sniffed", "text/plain", `  {"version": "https://jsonfeed.org/version/1.1"}`, true},
+		{"sniffed after a BOM", "application/octet-stream", "\xEF\xBB\xBF{\"version\": \"1.1\"}", true},
+		{"XML", "text/plain", `<?xml version="1.0"?><rss/>`, false},
+		{"XML after a BOM", "", "\xEF\xBB\xBF<rss/>", false},
+		{"empty", "", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isJSONFeed(tt.contentType, []byte(tt.body)); got != tt.want {
+				t.Errorf("isJSONFeed(%q, %q) = %v, want %v", tt.contentType, tt.body, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseJSONFeed(t *testing.T) {
+	const body = `{
+	"version": "https://jsonfeed.org/version/1.1",
+	"title": "Example &amp; Co",
+	"home_page_url": "https://example.com/",
+	"description": "Posts",
+	"language": "en",
+	"items": [
+		{
+			"id": "1",
+			"url": "https://example.com/1",
+			"title": "First",
+			"summary": "Short",
+			"content_html": "<p>Long</p>",
+			"date_published": "2006-01-02T15:04:05Z"
+		},
+		{
+			"id": "https://example.com/2",
+			"title": "Second",
+			"content_text": "Plain",
+			"date_modified": "2006-01-03T15:04:05Z"
+		}
+	]
+}`
+	for name, contentType := range map[string]string{
+		"feed+json":     "application/feed+json",
+		"text with BOM": "text/plain",
+	} {
+		t.Run(name, func(t *testing.T) {
+			input := body
+			if contentType == "text/plain" {
+				input = "\xEF\xBB\xBF" + body
+			}
+			feed, err := parseFeed(strings.NewReader(input), contentType, 10)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			channel := feed.Channel
+			if channel.Title != "Example & Co" || channel.Link != "https://example.com/" ||
+				channel.Description != "Posts" || channel.Language != "en" {
+				t.Errorf("got channel %q %q %q %q", channel.Title, channel.Link, channel.Description, channel.Language)
+			}
+			if len(channel.Item) != 2 {
+				t.Fatalf("got %d items, want 2", len(channel.Item))
+			}
+			first, second := channel.Item[0], channel.Item[1]
+			if first.GUID != "1" || first.Link != "https://example.com/1" || first.Title != "First" ||
+				first.Description != "Short" || first.Content != "<p>Long</p>" || first.PubDate != "2006-01-02T15:04:05Z" {
+				t.Errorf("first item: got %+v", first)
+			}
+
+			// Without a url the id is the link, without a summary the text
+			// content is the description, and without a publish date the
+			// modification date is used
+			if second.Link != "https://example.com/2" || second.Description != "Plain" ||
+				second.Content != "Plain" || second.PubDate != "2006-01-03T15:04:05Z" {
+				t.Errorf("second item: got %+v", second)
+			}
+		})
+	}
+
+}
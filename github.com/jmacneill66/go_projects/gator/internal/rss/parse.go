@@ -0,0 +1,442 @@
+package rss
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"strings"
+)
+
+// ErrFeedTooLarge is returned when a feed exceeds the maximum body size or
+// item count.
+var ErrFeedTooLarge = errors.New("feed too large")
+
+// Namespaces of elements and attributes read outside struct tags.
+const (
+	atomNamespace    = "http://www.w3.org/2005/Atom"
+	contentNamespace = "http://purl.org/rss/1.0/modules/content/"
+	dcNamespace      = "http://purl.org/dc/elements/1.1/"
+	itunesNamespace  = "http://www.itunes.com/dtds/podcast-1.0.dtd"
+	rdfNamespace     = "http://www.w3.org/1999/02/22-rdf-syntax-ns#"
+	syNamespace      = "http://purl.org/rss/1.0/modules/syndication/"
+	xmlNamespace     = "http://www.w3.org/XML/1998/namespace"
+)
+
+// parseFeed detects the feed format from the Content-Type or the start of
+// the body and parses it, reading items one at a time as they stream in.
+// At most maxItems items are accepted.
+func parseFeed(r io.Reader, contentType string, maxItems int) (*RSSFeed, error) {
+	br := bufio.NewReader(r)
+	head, err := br.Peek(512)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, bufio.ErrBufferFull) {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	var feed *RSSFeed
+	if isJSONFeed(contentType, head) {
+		feed, err = parseJSONFeed(br, maxItems)
+	} else {
+		feed, err = parseXMLFeed(br, contentType, maxItems)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	normalize(feed)
+	return feed, nil
+}
+
+// parseJSONFeed decodes a JSON Feed document.
+func parseJSONFeed(r *bufio.Reader, maxItems int) (*RSSFeed, error) {
+	// encoding/json rejects the byte order mark some servers prepend
+	if head, _ := r.Peek(len(utf8BOM)); bytes.Equal(head, utf8BOM) {
+		r.Discard(len(utf8BOM))
+	}
+
+	var feed jsonFeed
+	if err := json.NewDecoder(r).Decode(&feed); err != nil {
+		return nil, fmt.Errorf("failed to parse JSON Feed: %w", err)
+	}
+	if len(feed.Items) > maxItems {
+		return nil, fmt.Errorf("%w: more than %d items", ErrFeedTooLarge, maxItems)
+	}
+	return feed.toRSS(), nil
+}
+
+// parseXMLFeed streams an RSS 2.0, RSS 1.0 (RDF) or Atom document.
+func parseXMLFeed(r *bufio.Reader, contentType string, maxItems int) (*RSSFeed, error) {
+	decoder, err := newXMLDecoder(r, contentType)
+	if err != nil {
+		return nil, err
+	}
+
+	root, err := rootElement(decoder)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse XML: %w", err)
+	}
+
+	p := &feedParser{decoder: decoder, maxItems: maxItems, feed: &RSSFeed{}}
+	switch root.Name.Local {
+	case "rss":
+		err = p.parseRSS()
+	case "feed":
+		err = p.parseAtom(root)
+	case "RDF":
+		err = p.parseRDF()
+	default:
+		return nil, fmt.Errorf("unsupported feed format: <%s>", root.Name.Local)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s feed: %w", root.Name.Local, err)
+	}
+	return p.feed, nil
+}
+
+// rootElement reads up to and including the document's root element.
+func rootElement(decoder *xml.Decoder) (xml.StartElement, error) {
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return xml.StartElement{}, errors.New("document has no root element")
+			}
+			return xml.StartElement{}, err
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			return start, nil
+		}
+	}
+}
+
+// feedParser builds an RSSFeed from a stream of XML tokens.
+type feedParser struct {
+	decoder  *xml.Decoder
+	maxItems int
+	feed     *RSSFeed
+}
+
+// addItem appends an item, enforcing the maximum item count.
+func (p *feedParser) addItem(item RSSItem) error {
+	if len(p.feed.Channel.Item) >= p.maxItems {
+		return fmt.Errorf("%w: more than %d items", ErrFeedTooLarge, p.maxItems)
+	}
+	p.feed.Channel.Item = append(p.feed.Channel.Item, item)
+	return nil
+}
+
+// eachChild calls fn for each child element of the element that was just
+// opened, until its end tag. fn must consume the element it is given.
+func (p *feedParser) eachChild(fn func(start xml.StartElement) error) error {
+	for {
+		token, err := p.decoder.Token()
+		if err != nil {
+			return err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			if err := fn(t); err != nil {
+				return err
+			}
+		case xml.EndElement:
+			return nil
+		}
+	}
+}
+
+// parseRSS reads the <channel> of an RSS 2.0 document.
+func (p *feedParser) parseRSS() error {
+	channel := &p.feed.Channel
+	return p.eachChild(func(start xml.StartElement) error {
+		if start.Name.Local != "channel" {
+			return p.decoder.Skip()
+		}
+		return p.eachChild(func(start xml.StartElement) error {
+			switch {
+			case start.Name.Local == "item":
+				return p.parseItem(start)
+			case start.Name.Local == "title" && start.Name.Space == "":
+				return p.decoder.DecodeElement(&channel.Title, &start)
+			case start.Name.Local == "link" && start.Name.Space == "":
+				return p.decoder.DecodeElement(&channel.Link, &start)
+			case start.Name.Local == "description" && start.Name.Space == "":
+				return p.decoder.DecodeElement(&channel.Description, &start)
+			case start.Name.Local == "language" && start.Name.Space == "":
+				return p.decoder.DecodeElement(&channel.Language, &start)
+			case start.Name.Local == "generator" && start.Name.Space == "":
+				return p.decoder.DecodeElement(&channel.Generator, &start)
+			case start.Name.Local == "ttl" && start.Name.Space == "":
+				return p.decoder.DecodeElement(&channel.TTL, &start)
+			case start.Name.Space == syNamespace:
+				return p.parseSyndication(start)
+			case start.Name.Local == "image" && start.Name.Space == "":
+				var image struct {
+					URL string `xml:"url"`
+				}
+				if err := p.decoder.DecodeElement(&image, &start); err != nil {
+					return err
+				}
+				channel.ImageURL = image.URL
+				return nil
+			case start.Name.Local == "link" && start.Name.Space == atomNamespace:
+				// RSS feeds advertise WebSub hubs with atom:link
+				var link atomLink
+				if err := p.decoder.DecodeElement(&link, &start); err != nil {
+					return err
+				}
+				switch {
+				case link.Rel == "hub" && channel.Hub == "":
+					channel.Hub = strings.TrimSpace(link.Href)
+				case link.Rel == "self" && channel.Self == "":
+					channel.Self = strings.TrimSpace(link.Href)
+				}
+				return nil
+			case start.Name.Local == "image" && start.Name.Space == itunesNamespace:
+				// Podcasts often only have artwork in itunes:image
+				var image ITunesImage
+				if err := p.decoder.DecodeElement(&image, &start); err != nil {
+					return err
+				}
+				if channel.ImageURL == "" {
+					channel.ImageURL = image.Href
+				}
+				return nil
+			default:
+				return p.decoder.Skip()
+			}
+		})
+	})
+}
+
+// parseItem reads an RSS 2.0 or RSS 1.0 <item>. Its core fields are only
+// taken from children in the item's own namespace, so extension elements
+// like <media:title> or <atom:link> cannot overwrite them.
+func (p *feedParser) parseItem(item xml.StartElement) error {
+	var i RSSItem
+	for _, attr := range item.Attr {
+		if attr.Name.Space == rdfNamespace && attr.Name.Local == "about" {
+			i.About = attr.Value
+		}
+	}
+	err := p.eachChild(func(start xml.StartElement) error {
+		switch start.Name.Space {
+		case item.Name.Space:
+			switch start.Name.Local {
+			case "guid":
+				return p.decoder.DecodeElement(&i.GUID, &start)
+			case "title":
+				return p.decoder.DecodeElement(&i.Title, &start)
+			case "link":
+				return p.decoder.DecodeElement(&i.Link, &start)
+			case "description":
+				return p.decoder.DecodeElement(&i.Description, &start)
+			case "pubDate":
+				return p.decoder.DecodeElement(&i.PubDate, &start)
+			case "enclosure":
+				var enclosure Enclosure
+				if err := p.decoder.DecodeElement(&enclosure, &start); err != nil {
+					return err
+				}
+				i.Enclosures = append(i.Enclosures, enclosure)
+				return nil
+			}
+		case contentNamespace:
+			if start.Name.Local == "encoded" {
+				return p.decoder.DecodeElement(&i.Content, &start)
+			}
+		case dcNamespace:
+			switch start.Name.Local {
+			case "creator":
+				return p.decoder.DecodeElement(&i.Creator, &start)
+			case "date":
+				return p.decoder.DecodeElement(&i.DCDate, &start)
+			}
+		case itunesNamespace:
+			switch start.Name.Local {
+			case "duration":
+				return p.decoder.DecodeElement(&i.ITunesDuration, &start)
+			case "episode":
+				return p.decoder.DecodeElement(&i.ITunesEpisode, &start)
+			case "image":
+				return p.decoder.DecodeElement(&i.ITunesImage, &start)
+			}
+		}
+		return p.decoder.Skip()
+	})
+	if err != nil {
+		return err
+	}
+	return p.addItem(i)
+}
+
+// parseAtom reads the children of an Atom <feed>.
+func (p *feedParser) parseAtom(root xml.StartElement) error {
+	var atom atomFeed
+	err := p.eachChild(func(start xml.StartElement) error {
+		if start.Name.Space == syNamespace {
+			return p.parseSyndication(start)
+		}
+		switch start.Name.Local {
+		case "entry":
+			var entry atomEntry
+			if err := p.decoder.DecodeElement(&entry, &start); err != nil {
+				return err
+			}
+			return p.addItem(entry.toItem())
+		case "title":
+			return p.decoder.DecodeElement(&atom.Title, &start)
+		case "subtitle":
+			return p.decoder.DecodeElement(&atom.Subtitle, &start)
+		case "logo":
+			return p.decoder.DecodeElement(&atom.Logo, &start)
+		case "icon":
+			return p.decoder.DecodeElement(&atom.Icon, &start)
+		case "generator":
+			return p.decoder.DecodeElement(&atom.Generator, &start)
+		case "link":
+			var link atomLink
+			if err := p.decoder.DecodeElement(&link, &start); err != nil {
+				return err
+			}
+			atom.Links = append(atom.Links, link)
+			return nil
+		default:
+			return p.decoder.Skip()
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	p.feed.Channel.Title = atom.Title
+	p.feed.Channel.Link = alternateLink(atom.Links)
+	p.feed.Channel.Description = atom.Subtitle
+	p.feed.Channel.Generator = atom.Generator
+	p.feed.Channel.Hub = relLink(atom.Links, "hub")
+	p.feed.Channel.Self = relLink(atom.Links, "self")
+
+	// Prefer the larger logo over the icon
+	p.feed.Channel.ImageURL = atom.Logo
+	if p.feed.Channel.ImageURL == "" {
+		p.feed.Channel.ImageURL = atom.Icon
+	}
+
+	// Atom gives the language as xml:lang on the root element
+	for _, attr := range root.Attr {
+		if attr.Name.Space == xmlNamespace && attr.Name.Local == "lang" {
+			p.feed.Channel.Language = attr.Value
+		}
+	}
+	return nil
+}
+
+// parseRDF reads the <channel> and <item> children of an rdf:RDF root.
+func (p *feedParser) parseRDF() error {
+	return p.eachChild(func(start xml.StartElement) error {
+		switch start.Name.Local {
+		case "item":
+			return p.parseItem(start)
+		case "channel":
+			var channel rdfChannel
+			if err := p.decoder.DecodeElement(&channel, &start); err != nil {
+				return err
+			}
+			p.feed.Channel.Title = channel.Title
+			p.feed.Channel.Link = channel.Link
+			p.feed.Channel.Description = channel.Description
+			p.feed.Channel.Language = channel.Language
+			p.feed.Channel.ImageURL = channel.Image.Resource
+			p.feed.Channel.UpdatePeriod = channel.UpdatePeriod
+			p.feed.Channel.UpdateFrequency = channel.UpdateFrequency
+			return nil
+		default:
+			return p.decoder.Skip()
+		}
+	})
+}
+
+// parseSyndication reads an update hint from the syndication module.
+func (p *feedParser) parseSyndication(start xml.StartElement) error {
+	switch start.Name.Local {
+	case "updatePeriod":
+		return p.decoder.DecodeElement(&p.feed.Channel.UpdatePeriod, &start)
+	case "updateFrequency":
+		return p.decoder.DecodeElement(&p.feed.Channel.UpdateFrequency, &start)
+	default:
+		return p.decoder.Skip()
+	}
+}
+
+// normalize unescapes HTML entities in titles and descriptions and fills in
+// fallback fields.
+func normalize(feed *RSSFeed) {
+	feed.Channel.Title = html.UnescapeString(feed.Channel.Title)
+	feed.Channel.Description = html.UnescapeString(feed.Channel.Description)
+	feed.Channel.Language = strings.TrimSpace(feed.Channel.Language)
+	feed.Channel.Generator = strings.TrimSpace(feed.Channel.Generator)
+	feed.Channel.ImageURL = strings.TrimSpace(feed.Channel.ImageURL)
+
+	for i := range feed.Channel.Item {
+		feed.Channel.Item[i].Title = html.UnescapeString(feed.Channel.Item[i].Title)
+		feed.Channel.Item[i].Description = html.UnescapeString(feed.Channel.Item[i].Description)
+		feed.Channel.Item[i].Creator = strings.TrimSpace(feed.Channel.Item[i].Creator)
+
+		// RSS 1.0 identifies items by their rdf:about attribute
+		feed.Channel.Item[i].GUID = strings.TrimSpace(feed.Channel.Item[i].GUID)
+		if feed.Channel.Item[i].GUID == "" {
+			feed.Channel.Item[i].GUID = strings.TrimSpace(feed.Channel.Item[i].About)
+		}
+
+		// Fall back to the Dublin Core date when there is no pubDate
+		if feed.Channel.Item[i].PubDate == "" {
+			feed.Channel.Item[i].PubDate = feed.Channel.Item[i].DCDate
+		}
+	}
+}
+
+// cappedReader fails with ErrFeedTooLarge once more than limit bytes have
+// been read, instead of silently truncating like io.LimitReader.
+type cappedReader struct {
+	r         io.Reader
+	limit     int64
+	remaining int64
+}
+
+// newCappedReader returns a reader that allows at most limit bytes.
+func newCappedReader(r io.Reader, limit int64) *cappedReader {
+	// One extra byte tells a body of exactly limit bytes from a larger one
+	return &cappedReader{r: r, limit: limit, remaining: limit + 1}
+}
+
+// Read reads from the underlying reader until the limit is exceeded.
+func (c *cappedReader) Read(b []byte) (int, error) {
+	if c.remaining <= 0 {
+		return 0, fmt.Errorf("%w: body exceeds %d bytes", ErrFeedTooLarge, c.limit)
+	}
+	if int64(len(b)) > c.remaining {
+		b = b[:c.remaining]
+	}
+	n, err := c.r.Read(b)
+	c.remaining -= int64(n)
+	if c.remaining <= 0 {
+		return n - 1, fmt.Errorf("%w: body exceeds %d bytes", ErrFeedTooLarge, c.limit)
+	}
+	return n, err
+}
+
+// countingReader counts the bytes read through it.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+// Read reads from the underlying reader, adding to the count.
+func (c *countingReader) Read(b []byte) (int, error) {
+	n, err := c.r.Read(b)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,122 @@
+package rss
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestCappedReader(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		limit   int64
+		wantErr bool
+	}{
+		{"empty", "", 10, false},
+		{"under the limit", "hello", 10, false},
+		{"at the limit", "0123456789", 10, false},
+		{"one byte over", "0123456789a", 10, true},
+		{"far over", strings.Repeat("x", 100), 10, true},
+		{"zero limit", "x", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Read a byte at a time too, so the limit is hit mid-read and on
+			// a read boundary
+			readers := map[string]io.Reader{
+				"whole":    strings.NewReader(tt.body),
+				"one byte": iotest.OneByteReader(strings.NewReader(tt.body)),
+			}
+			for name, r := range readers {
+				got, err := io.ReadAll(newCappedReader(r, tt.limit))
+				if tt.wantErr {
+					if !errors.Is(err, ErrFeedTooLarge) {
+						t.Errorf("%s: got error %v, want ErrFeedTooLarge", name, err)
+					}
+					if int64(len(got)) > tt.limit {
+						t.Errorf("%s: read %d bytes, more than the limit of %d", name, len(got), tt.limit)
+					}
+					continue
+				}
+				if err != nil || string(got) != tt.body {
+					t.Errorf("%s: got %q, %v; want %q", name, got, err, tt.body)
+				}
+			}
+		})
+	}
+}
+
+func TestParseRSSItemExtensions(t *testing.T) {
+	const body = `<?xml version="1.0"?>
+<rss version="2.0" xmlns:media="http://search.yahoo.com/mrss/" xmlns:atom="http://www.w3.org/2005/Atom"
+	xmlns:dc="http://purl.org/dc/elements/1.1/">
+<channel><title>Videos</title><link>https://example.com/</link>
+<item>
+	<title>Episode 1</title>
+	<link>https://example.com/1</link>
+	<description>The first episode</description>
+	<guid>ep-1</guid>
+	<dc:creator>Ada</dc:creator>
+	<media:title>Episode 1 (HD)</media:title>
+	<media:description>Video description</media:description>
+	<atom:link rel="alternate" href="https://mirror.example.com/1"/>
+	<media:content url="https://example.com/1.mp4" type="video/mp4">
+		<media:title>Nested title</media:title>
+	</media:content>
+</item>
+</channel></rss>`
+	feed, err := parseFeed(strings.NewReader(body), "application/rss+xml", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(feed.Channel.Item) != 1 {
+		t.Fatalf("got %d items, want 1", len(feed.Channel.Item))
+	}
+	item := feed.Channel.Item[0]
+	want := RSSItem{
+		GUID:        "ep-1",
+		Title:       "Episode 1",
+		Link:        "https://example.com/1",
+		Description: "The first episode",
+		Creator:     "Ada",
+	}
+	if item.GUID != want.GUID || item.Title != want.Title || item.Link != want.Link ||
+		item.Description != want.Description || item.Creator != want.Creator {
+		t.Errorf("got %+v, want %+v", item, want)
+	}
+}
+
+func TestParseAuthor(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+	}{
+		{"RSS", "application/rss+xml", `<rss version="2.0" xmlns:dc="http://purl.org/dc/elements/1.1/">
+<channel><item><guid>1</guid><dc:creator> Ada </dc:creator></item></channel></rss>`},
+		{"RDF", "application/rdf+xml", `<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#"
+	xmlns="http://purl.org/rss/1.0/" xmlns:dc="http://purl.org/dc/elements/1.1/">
+<item rdf:about="1"><dc:creator>Ada</dc:creator></item></rdf:RDF>`},
+		{"Atom", "application/atom+xml", `<feed xmlns="http://www.w3.org/2005/Atom">
+<entry><id>1</id><author><name>Ada</name><email>ada@example.com</email></author></entry></feed>`},
+		{"JSON Feed 1.0", "application/feed+json", `{"items": [{"id": "1", "author": {"name": "Ada"}}]}`},
+		{"JSON Feed 1.1", "application/feed+json", `{"items": [{"id": "1", "authors": [{"name": "Ada"}, {"name": "Bob"}]}]}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			feed, err := parseFeed(strings.NewReader(tt.body), tt.contentType, 10)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(feed.Channel.Item) != 1 {
+				t.Fatalf("got %d items, want 1", len(feed.Channel.Item))
+			}
+			if got := feed.Channel.Item[0].Creator; got != "Ada" {
+				t.Errorf("got author %q, want Ada", got)
+			}
+		})
+	}
+}
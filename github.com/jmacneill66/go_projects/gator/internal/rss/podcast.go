@@ -0,0 +1,44 @@
+package rss
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Enclosure represents a media file attached to an item.
+type Enclosure struct {
+	URL    string `xml:"url,attr"`
+	Length string `xml:"length,attr"`
+	Type   string `xml:"type,attr"`
+}
+
+// ITunesImage represents an <itunes:image href="..."> element.
+type ITunesImage struct {
+	Href string `xml:"href,attr"`
+}
+
+// ParseDuration parses an itunes:duration value, which may be given as
+// seconds, MM:SS or HH:MM:SS, and returns the total number of seconds.
+func ParseDuration(value string) (int, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+
+	parts := strings.Split(value, ":")
+	if len(parts) > 3 {
+		return 0, false
+	}
+
+	total := 0
+	for _, part := range parts {
+		// Some feeds use fractional seconds; keep the whole part
+		part, _, _ = strings.Cut(part, ".")
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return 0, false
+		}
+		total = total*60 + n
+	}
+	return total, true
+}
@@ -0,0 +1,32 @@
+package rss
+
+import "testing"
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		value  string
+		want   int
+		wantOK bool
+	}{
+		{"3600", 3600, true},
+		{"45:30", 45*60 + 30, true},
+		{"1:02:03", 3600 + 2*60 + 3, true},
+		{" 05:00 ", 300, true},
+		{"90.5", 90, true},
+		{"1:02:03.750", 3723, true},
+		{"0", 0, true},
+		{"", 0, false},
+		{"1:2:3:4", 0, false},
+		{"an hour", 0, false},
+		{"-5", 0, false},
+		{"1::30", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, ok := ParseDuration(tt.value)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("ParseDuration(%q) = %d, %v; want %d, %v", tt.value, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
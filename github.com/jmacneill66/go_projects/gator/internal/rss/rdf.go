@@ -0,0 +1,18 @@
+package rss
+
+// rdfChannel represents the <channel> of an RSS 1.0 document. Unlike RSS 2.0,
+// its <item> elements sit next to the channel under the rdf:RDF root.
+type rdfChannel struct {
+	Title       string `xml:"title"`
+	Link        string `xml:"link"`
+	Description string `xml:"description"`
+	Language    string `xml:"http://purl.org/dc/elements/1.1/ language"`
+
+	UpdatePeriod    string `xml:"http://purl.org/rss/1.0/modules/syndication/ updatePeriod"`
+	UpdateFrequency string `xml:"http://purl.org/rss/1.0/modules/syndication/ updateFrequency"`
+
+	// The channel's <image> refers to the image URL with rdf:resource
+	Image struct {
+		Resource string `xml:"http://www.w3.org/1999/02/22-rdf-syntax-ns# resource,attr"`
+	} `xml:"image"`
+}
@@ -0,0 +1,54 @@
+package rss
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseRDF(t *testing.T) {
+	const body = `<?xml version="1.0"?>
+<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#"
+	xmlns="http://purl.org/rss/1.0/" xmlns:dc="http://purl.org/dc/elements/1.1/">
+	<channel rdf:about="https://example.com/index.rdf">
+		<title>Example</title>
+		<link>https://example.com/</link>
+		<description>Old school</description>
+		<dc:language>en</dc:language>
+		<items><rdf:Seq><rdf:li rdf:resource="https://example.com/1"/></rdf:Seq></items>
+	</channel>
+	<item rdf:about="https://example.com/1">
+		<title>First</title>
+		<link>https://example.com/1</link>
+		<description>One</description>
+		<dc:date>2006-01-02T15:04:05Z</dc:date>
+		<dc:creator>Ada</dc:creator>
+	</item>
+	<item rdf:about="https://example.com/2">
+		<title>Second</title>
+		<link>https://example.com/2</link>
+	</item>
+</rdf:RDF>`
+	feed, err := parseFeed(strings.NewReader(body), "application/rdf+xml", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	channel := feed.Channel
+	if channel.Title != "Example" || channel.Link != "https://example.com/" ||
+		channel.Description != "Old school" || channel.Language != "en" {
+		t.Errorf("got channel %q %q %q %q", channel.Title, channel.Link, channel.Description, channel.Language)
+	}
+	if len(channel.Item) != 2 {
+		t.Fatalf("got %d items, want 2", len(channel.Item))
+	}
+
+	// Items are identified by rdf:about and dated with dc:date
+	first := channel.Item[0]
+	if first.GUID != "https://example.com/1" || first.Title != "First" || first.Link != "https://example.com/1" ||
+		first.Description != "One" || first.PubDate != "2006-01-02T15:04:05Z" {
+		t.Errorf("first item: got %+v", first)
+	}
+	if second := channel.Item[1]; second.GUID != "https://example.com/2" || second.PubDate != "" {
+		t.Errorf("second item: got %+v", second)
+	}
+}
@@ -2,11 +2,14 @@ package rss
 
 import (
 	"context"
-	"encoding/xml"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
-	"html"
 	"io"
 	"net/http"
+	"strings"
+	"time"
 )
 
 // RSSFeed represents the overall RSS structure.
@@ -15,57 +18,269 @@ type RSSFeed struct {
 		Title       string    `xml:"title"`
 		Link        string    `xml:"link"`
 		Description string    `xml:"description"`
+		Language    string    `xml:"language"`
+		Generator   string    `xml:"generator"`
+		ImageURL    string    `xml:"-"`
 		Item        []RSSItem `xml:"item"`
+
+		// WebSub hub and the feed's canonical (self) URL, when advertised
+		Hub  string `xml:"-"`
+		Self string `xml:"-"`
+
+		// Publisher hints on how often to poll: the RSS <ttl> in minutes,
+		// and the syndication module's updatePeriod and updateFrequency
+		TTL             string `xml:"ttl"`
+		UpdatePeriod    string `xml:"http://purl.org/rss/1.0/modules/syndication/ updatePeriod"`
+		UpdateFrequency string `xml:"http://purl.org/rss/1.0/modules/syndication/ updateFrequency"`
 	} `xml:"channel"`
 }
 
 // RSSItem represents an individual item in the RSS feed.
 type RSSItem struct {
+	GUID        string `xml:"guid"`
+	About       string `xml:"http://www.w3.org/1999/02/22-rdf-syntax-ns# about,attr"`
 	Title       string `xml:"title"`
 	Link        string `xml:"link"`
 	Description string `xml:"description"`
 	PubDate     string `xml:"pubDate"`
+
+	// Content is the full article body, when the feed provides one
+	// separately from the description
+	Content string `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+
+	// Dublin Core fields, used by RSS 1.0 and by many RSS 2.0 feeds
+	Creator string `xml:"http://purl.org/dc/elements/1.1/ creator"`
+	DCDate  string `xml:"http://purl.org/dc/elements/1.1/ date"`
+
+	// Podcast fields
+	Enclosures     []Enclosure `xml:"enclosure"`
+	ITunesDuration string      `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd duration"`
+	ITunesEpisode  string      `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd episode"`
+	ITunesImage    ITunesImage `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd image"`
+}
+
+// Key returns the item's identity within its feed: its guid (or Atom id,
+// JSON Feed id or RDF about), or a SHA-256 of its link and title when the
+// feed provides none.
+func (i RSSItem) Key() string {
+	if i.GUID != "" {
+		return i.GUID
+	}
+	sum := sha256.Sum256([]byte(i.Link + "\n" + i.Title))
+	return hex.EncodeToString(sum[:])
+}
+
+// FetchOptions holds per-request settings for FetchFeed.
+type FetchOptions struct {
+	// Cache validators from the previous fetch, sent as conditional headers
+	ETag         string
+	LastModified string
+
+	// Headers are extra request headers, such as the feed's credentials
+	Headers map[string]string
+}
+
+// FetchResult is the outcome of a successful FetchFeed call.
+type FetchResult struct {
+	// StatusCode is the HTTP status of the response, or 0 for feeds not
+	// fetched over HTTP
+	StatusCode int
+
+	// Feed is nil when NotModified is true
+	Feed        *RSSFeed
+	NotModified bool
+
+	// Cache validators returned by the server, to be sent on the next fetch
+	ETag         string
+	LastModified string
+
+	// Redirects lists the redirects followed to reach the feed, in order
+	Redirects []Redirect
+
+	// MaxAge is the Cache-Control max-age of the response, or 0
+	MaxAge time.Duration
+
+	// Bytes is the size of the body that was read, 0 when NotModified
+	Bytes int64
+}
+
+// Redirect is one redirect followed while fetching a feed.
+type Redirect struct {
+	From       string
+	To         string
+	StatusCode int
+}
+
+// PermanentURL returns the URL the feed has permanently moved to: the end
+// of the leading run of 301 and 308 redirects. It returns "" when the first
+// redirect is temporary or there are none.
+func (r *FetchResult) PermanentURL() string {
+	var target string
+	for _, redirect := range r.Redirects {
+		if redirect.StatusCode != http.StatusMovedPermanently && redirect.StatusCode != http.StatusPermanentRedirect {
+			break
+		}
+		target = redirect.To
+	}
+	return target
+}
+
+// ErrGone is returned when the server reports that a feed has been removed
+// for good with 410 Gone.
+var ErrGone = errors.New("feed gone")
+
+// StatusError is returned when the server answers with a status other than
+// success or 304 Not Modified. A 410 Gone StatusError matches ErrGone.
+type StatusError struct {
+	StatusCode int
+	Status     string // e.g. "500 Internal Server Error"
 }
 
-// FetchFeed fetches and parses an RSS feed.
-func FetchFeed(ctx context.Context, feedURL string) (*RSSFeed, error) {
+func (e *StatusError) Error() string {
+	if e.StatusCode == http.StatusGone {
+		return fmt.Sprintf("%v: %s", ErrGone, e.Status)
+	}
+	return fmt.Sprintf("unexpected status: %s", e.Status)
+}
+
+// Is reports whether the error is ErrGone.
+func (e *StatusError) Is(target error) bool {
+	return target == ErrGone && e.StatusCode == http.StatusGone
+}
+
+// FetchFeed fetches and parses an RSS 2.0, RSS 1.0 (RDF), Atom or JSON feed.
+// A 304 Not Modified response is a successful fetch that skips parsing.
+func (c *Client) FetchFeed(ctx context.Context, feedURL string, opts FetchOptions) (*FetchResult, error) {
+	// Cancel the fetch when the response body stalls for the read timeout
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	// Remember the per-feed headers, so redirects to other hosts drop them
+	if len(opts.Headers) > 0 {
+		names := make([]string, 0, len(opts.Headers))
+		for name := range opts.Headers {
+			names = append(names, name)
+		}
+		ctx = context.WithValue(ctx, feedHeadersKey{}, names)
+	}
+
 	// Create an HTTP request with context
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, feedURL, nil)
+	req, err := c.newRequest(ctx, feedURL)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return nil, err
+	}
+
+	for name, value := range opts.Headers {
+		req.Header.Set(name, value)
 	}
 
-	// Set User-Agent header
-	req.Header.Set("User-Agent", "gator")
+	// Make the request conditional when we have validators from a previous fetch
+	if opts.ETag != "" {
+		req.Header.Set("If-None-Match", opts.ETag)
+	}
+	if opts.LastModified != "" {
+		req.Header.Set("If-Modified-Since", opts.LastModified)
+	}
 
 	// Make the HTTP request
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := c.httpClient.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch feed: %w", err)
 	}
 	defer resp.Body.Close()
 
-	// Read response body
-	body, err := io.ReadAll(resp.Body)
+	result := &FetchResult{
+		StatusCode:   resp.StatusCode,
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+		Redirects:    redirects(resp),
+		MaxAge:       cacheMaxAge(resp.Header),
+	}
+
+	// Nothing changed since the last fetch; keep the previous validators if
+	// the server did not repeat them
+	if resp.StatusCode == http.StatusNotModified {
+		result.NotModified = true
+		if result.ETag == "" {
+			result.ETag = opts.ETag
+		}
+		if result.LastModified == "" {
+			result.LastModified = opts.LastModified
+		}
+		return result, nil
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, &StatusError{StatusCode: resp.StatusCode, Status: resp.Status}
+	}
+
+	stall := newStallReader(resp.Body, c.readTimeout, cancel, func() error { return context.Cause(ctx) })
+	defer stall.stop()
+	body := &countingReader{r: stall}
+	feed, err := c.Parse(body, resp.Header.Get("Content-Type"))
+	result.Bytes = body.n
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
+		return result, err
 	}
 
-	// Unmarshal XML into RSSFeed struct
-	var feed RSSFeed
-	if err := xml.Unmarshal(body, &feed); err != nil {
-		return nil, fmt.Errorf("failed to parse XML: %w", err)
+	// WebSub hubs may also be advertised in Link headers
+	if feed.Channel.Hub == "" {
+		feed.Channel.Hub = headerLink(resp.Header, "hub")
+	}
+	if feed.Channel.Self == "" {
+		feed.Channel.Self = headerLink(resp.Header, "self")
 	}
 
-	// Unescape HTML entities in titles and descriptions
-	feed.Channel.Title = html.UnescapeString(feed.Channel.Title)
-	feed.Channel.Description = html.UnescapeString(feed.Channel.Description)
+	result.Feed = feed
+	return result, nil
+}
 
-	for i := range feed.Channel.Item {
-		feed.Channel.Item[i].Title = html.UnescapeString(feed.Channel.Item[i].Title)
-		feed.Channel.Item[i].Description = html.UnescapeString(feed.Channel.Item[i].Description)
+// redirects returns the redirects that led to a response, oldest first.
+func redirects(resp *http.Response) []Redirect {
+	var chain []Redirect
+	for req := resp.Request; req != nil && req.Response != nil; req = req.Response.Request {
+		chain = append([]Redirect{{
+			From:       req.Response.Request.URL.String(),
+			To:         req.URL.String(),
+			StatusCode: req.Response.StatusCode,
+		}}, chain...)
 	}
+	return chain
+}
+
+// Parse parses a feed body, such as one pushed by a WebSub hub, with the
+// client's size and item limits. The body is parsed as it streams in.
+func (c *Client) Parse(r io.Reader, contentType string) (*RSSFeed, error) {
+	return c.parse(r, contentType)
+}
 
-	return &feed, nil
+// ReadBody reads a whole feed body, failing with ErrFeedTooLarge if it is
+// larger than the client's maximum body size.
+func (c *Client) ReadBody(r io.Reader) ([]byte, error) {
+	return io.ReadAll(newCappedReader(r, c.maxBodyBytes))
+}
+
+// headerLink returns the URL of the first Link header with the given rel.
+func headerLink(header http.Header, rel string) string {
+	for _, value := range header.Values("Link") {
+		for _, link := range strings.Split(value, ",") {
+			parts := strings.Split(link, ";")
+			target := strings.TrimSpace(parts[0])
+			if !strings.HasPrefix(target, "<") || !strings.HasSuffix(target, ">") {
+				continue
+			}
+			for _, param := range parts[1:] {
+				name, rels, ok := strings.Cut(strings.TrimSpace(param), "=")
+				if !ok || !strings.EqualFold(strings.TrimSpace(name), "rel") {
+					continue
+				}
+				for _, r := range strings.Fields(strings.Trim(rels, `"`)) {
+					if strings.EqualFold(r, rel) {
+						return strings.Trim(target, "<>")
+					}
+				}
+			}
+		}
+	}
+	return ""
 }
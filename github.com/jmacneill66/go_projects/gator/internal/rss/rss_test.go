@@ -0,0 +1,43 @@
+package rss
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestHeaderLink(t *testing.T) {
+	tests := []struct {
+		name  string
+		links []string
+		rel   string
+		want  string
+	}{
+		{"none", nil, "hub", ""},
+		{"single", []string{`<https://hub.example.com/>; rel="hub"`}, "hub", "https://hub.example.com/"},
+		{"unquoted rel", []string{`<https://hub.example.com/>; rel=hub`}, "hub", "https://hub.example.com/"},
+		{"case-insensitive", []string{`<https://hub.example.com/>; REL="Hub"`}, "hub", "https://hub.example.com/"},
+		{
+			"several in one header",
+			[]string{`<https://example.com/feed>; rel="self", <https://hub.example.com/>; rel="hub"`},
+			"hub", "https://hub.example.com/",
+		},
+		{
+			"several headers",
+			[]string{`<https://example.com/feed>; rel="self"`, `<https://hub.example.com/>; rel="hub"`},
+			"self", "https://example.com/feed",
+		},
+		{"space-separated rels", []string{`<https://example.com/feed>; rel="alternate self"`}, "self", "https://example.com/feed"},
+		{"first wins", []string{`<https://a.example.com/>; rel="hub", <https://b.example.com/>; rel="hub"`}, "hub", "https://a.example.com/"},
+		{"other rel", []string{`<https://example.com/>; rel="alternate"`}, "hub", ""},
+		{"rel in another param", []string{`<https://example.com/>; title="hub"`}, "hub", ""},
+		{"no brackets", []string{`https://hub.example.com/; rel="hub"`}, "hub", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{"Link": tt.links}
+			if got := headerLink(header, tt.rel); got != tt.want {
+				t.Errorf("headerLink(%q) = %q, want %q", tt.rel, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+// Package websub implements the subscriber side of WebSub, formerly known
+// as PubSubHubbub (https://www.w3.org/TR/websub/).
+package websub
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Request describes a subscription request sent to a hub.
+type Request struct {
+	Hub      string
+	Topic    string
+	Callback string
+
+	// Secret is used by the hub to sign pushed content
+	Secret string
+
+	// LeaseSeconds is the requested lease; the hub may choose another
+	LeaseSeconds int
+}
+
+// Subscribe asks a hub to push updates of the topic to the callback.
+// The hub confirms the subscription later with a verification request
+// to the callback.
+func Subscribe(ctx context.Context, client *http.Client, req Request) error {
+	form := url.Values{
+		"hub.mode":     {"subscribe"},
+		"hub.topic":    {req.Topic},
+		"hub.callback": {req.Callback},
+	}
+	if req.Secret != "" {
+		form.Set("hub.secret", req.Secret)
+	}
+	if req.LeaseSeconds > 0 {
+		form.Set("hub.lease_seconds", strconv.Itoa(req.LeaseSeconds))
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, req.Hub, strings.NewReader(form.Encode()))
+	if err != nil {
+		return fmt.Errorf("failed to create subscription request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("failed to send subscription request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// Hubs answer 202 Accepted, but some use other success codes
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("hub rejected subscription: %s", resp.Status)
+	}
+	return nil
+}
+
+// ValidSignature reports whether signature, the value of a push's
+// X-Hub-Signature header, is the HMAC of body with the secret.
+func ValidSignature(secret string, body []byte, signature string) bool {
+	method, sum, ok := strings.Cut(signature, "=")
+	if !ok {
+		return false
+	}
+
+	var newHash func() hash.Hash
+	switch strings.ToLower(method) {
+	case "sha1":
+		newHash = sha1.New
+	case "sha256":
+		newHash = sha256.New
+	case "sha384":
+		newHash = sha512.New384
+	case "sha512":
+		newHash = sha512.New
+	default:
+		return false
+	}
+
+	want, err := hex.DecodeString(sum)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(newHash, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(mac.Sum(nil), want)
+}
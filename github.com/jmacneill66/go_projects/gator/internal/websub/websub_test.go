@@ -0,0 +1,44 @@
+package websub
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"testing"
+)
+
+// signature returns an X-Hub-Signature value for body.
+func signature(method string, newHash func() hash.Hash, secret, body string) string {
+	mac := hmac.New(newHash, []byte(secret))
+	mac.Write([]byte(body))
+	return method + "=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestValidSignature(t *testing.T) {
+	const secret, body = "s3cret", "<feed/>"
+	tests := []struct {
+		name      string
+		signature string
+		want      bool
+	}{
+		{"sha256", signature("sha256", sha256.New, secret, body), true},
+		{"sha1", signature("sha1", sha1.New, secret, body), true},
+		{"upper-case method", signature("SHA256", sha256.New, secret, body), true},
+		{"other secret", signature("sha256", sha256.New, "other", body), false},
+		{"other body", signature("sha256", sha256.New, secret, "<feed></feed>"), false},
+		{"method mismatch", signature("sha1", sha256.New, secret, body), false},
+		{"unknown method", signature("md5", sha256.New, secret, body), false},
+		{"not hex", "sha256=zz", false},
+		{"no method", hex.EncodeToString([]byte(body)), false},
+		{"empty", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ValidSignature(secret, []byte(body), tt.signature); got != tt.want {
+				t.Errorf("ValidSignature(%q) = %v, want %v", tt.signature, got, tt.want)
+			}
+		})
+	}
+}
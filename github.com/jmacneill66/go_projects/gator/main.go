@@ -1,11 +1,13 @@
 package main
 
 import (
-	//"context"
+	"context"
 	"database/sql"
 	"fmt"
 	"log"
 	"os"
+	"os/signal"
+	"syscall"
 
 	_ "github.com/lib/pq" // PostgreSQL driver
 	//"github.com/google/uuid"
@@ -13,6 +15,7 @@ import (
 	"github.com/jmacneill66/go_projects/gator/internal/cli"
 	"github.com/jmacneill66/go_projects/gator/internal/config"
 	"github.com/jmacneill66/go_projects/gator/internal/database"
+	"github.com/jmacneill66/go_projects/gator/internal/rss"
 )
 
 func main() {
@@ -36,10 +39,26 @@ func main() {
 	// Initialize database queries
 	dbQueries := database.New(db)
 
+	// Create the shared HTTP client used for every feed fetch
+	client, err := rss.NewClient(cfg.Fetch)
+	if err != nil {
+		log.Fatalf("Error configuring fetch client: %v", err)
+	}
+
+	// Fetch http(s) feeds with the client and file:// feeds from disk
+	fetcher := rss.NewRouter()
+	fetcher.Handle("http", client)
+	fetcher.Handle("https", client)
+	fetcher.Handle("file", rss.NewFileFetcher(cfg.Fetch))
+
 	// Create a state struct holding the config
 	state := &cli.State{
-		DB:  dbQueries,
-		Cfg: &cfg,
+		DB:         dbQueries,
+		Conn:       db,
+		Cfg:        &cfg,
+		Client:     client,
+		Fetcher:    fetcher,
+		Discoverer: client,
 	}
 
 	// Initialize the command registry
@@ -50,11 +69,18 @@ func main() {
 	commands.Register("users", cli.HandlerUsers)
 	commands.Register("agg", cli.HandlerAgg)
 	commands.Register("feeds", cli.HandlerFeeds)
+	commands.Register("feedstats", cli.HandlerFeedStats)
 	commands.Register("addfeed", cli.MiddlewareLoggedIn(cli.HandlerAddFeed))
+	commands.Register("editfeed", cli.MiddlewareLoggedIn(cli.HandlerEditFeed))
 	commands.Register("follow", cli.MiddlewareLoggedIn(cli.HandlerFollow))
 	commands.Register("following", cli.MiddlewareLoggedIn(cli.HandlerFollowing))
 	commands.Register("unfollow", cli.MiddlewareLoggedIn(cli.HandlerUnfollow))
 	commands.Register("browse", cli.MiddlewareLoggedIn(cli.HandlerBrowse))
+	commands.Register("show", cli.MiddlewareLoggedIn(cli.HandlerShow))
+	commands.Register("episodes", cli.MiddlewareLoggedIn(cli.HandlerEpisodes))
+	commands.Register("podcasts", cli.MiddlewareLoggedIn(cli.HandlerEpisodes))
+	commands.Register("import-opml", cli.MiddlewareLoggedIn(cli.HandlerImportOPML))
+	commands.Register("export-opml", cli.MiddlewareLoggedIn(cli.HandlerExportOPML))
 	commands.Register("agg", cli.HandlerAgg)
 
 	// Parse command-line arguments
@@ -70,8 +96,21 @@ func main() {
 	// Create the command instance
 	cmd := cli.Command{Name: cmdName, Args: cmdArgs}
 
+	// Cancel the command on Ctrl+C, or when systemd or a container runtime
+	// sends SIGTERM, so it can finish or roll back what it is doing
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+
+	// Restore the default handling once the first signal arrives, so a
+	// second Ctrl+C kills a command that is slow to stop
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
 	// Run the command
-	if err := commands.Run(state, cmd); err != nil {
+	err = commands.Run(ctx, state, cmd)
+	stop()
+	if err != nil {
 		fmt.Println("Error:", err)
 		os.Exit(1)
 	}
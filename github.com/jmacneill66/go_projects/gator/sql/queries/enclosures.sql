@@ -0,0 +1,17 @@
+-- name: CreatePostEnclosure :exec
+INSERT INTO post_enclosures (id, created_at, updated_at, post_id, url, length, mime_type, duration_seconds, episode, image_url)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+ON CONFLICT (post_id, url) DO NOTHING;
+
+-- name: GetEpisodesForUser :many
+SELECT posts.title, posts.published_at, feeds.name AS feed_name,
+    post_enclosures.url, post_enclosures.length, post_enclosures.mime_type,
+    post_enclosures.duration_seconds, post_enclosures.episode, post_enclosures.image_url
+FROM post_enclosures
+JOIN posts ON post_enclosures.post_id = posts.id
+JOIN feeds ON posts.feed_id = feeds.id
+JOIN feed_follows ON feeds.id = feed_follows.feed_id
+JOIN users ON feed_follows.user_id = users.id
+WHERE users.name = $1
+ORDER BY posts.published_at DESC NULLS LAST, posts.created_at DESC
+LIMIT $2;
@@ -0,0 +1,10 @@
+-- name: GetFeedSettings :one
+SELECT id, name, url, user_id, auth_type, auth_username, auth_secret_env, headers,
+    min_fetch_interval_seconds, max_fetch_interval_seconds
+FROM feeds
+WHERE id = $1;
+
+-- name: UpdateFeedAuth :exec
+UPDATE feeds
+SET auth_type = $2, auth_username = $3, auth_secret_env = $4, headers = $5, updated_at = now()
+WHERE id = $1;
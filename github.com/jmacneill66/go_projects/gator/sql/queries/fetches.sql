@@ -0,0 +1,38 @@
+-- name: CreateFeedFetch :exec
+INSERT INTO feed_fetches (id, feed_id, started_at, finished_at, status_code, bytes, item_count, new_posts, error)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9);
+
+-- name: DeleteOldFeedFetches :exec
+DELETE FROM feed_fetches
+WHERE started_at < now() - make_interval(days => sqlc.arg(days)::integer);
+
+-- name: GetFeedFetchStats :many
+-- Summarises the fetches and published posts of each feed, or of one feed,
+-- over the last days days. Latencies are in seconds.
+SELECT feeds.id, feeds.name, feeds.url,
+    count(feed_fetches.id) AS attempts,
+    count(feed_fetches.id) FILTER (WHERE feed_fetches.error IS NULL) AS successes,
+    COALESCE(percentile_cont(0.5) WITHIN GROUP (
+        ORDER BY EXTRACT(EPOCH FROM feed_fetches.finished_at - feed_fetches.started_at)), 0)::float8 AS p50_seconds,
+    COALESCE(percentile_cont(0.9) WITHIN GROUP (
+        ORDER BY EXTRACT(EPOCH FROM feed_fetches.finished_at - feed_fetches.started_at)), 0)::float8 AS p90_seconds,
+    COALESCE(percentile_cont(0.99) WITHIN GROUP (
+        ORDER BY EXTRACT(EPOCH FROM feed_fetches.finished_at - feed_fetches.started_at)), 0)::float8 AS p99_seconds,
+    (
+        SELECT count(*) FROM posts
+        WHERE posts.feed_id = feeds.id
+        AND posts.published_at > now() - make_interval(days => sqlc.arg(days)::integer)
+    ) AS published_posts
+FROM feeds
+LEFT JOIN feed_fetches ON feed_fetches.feed_id = feeds.id
+    AND feed_fetches.started_at > now() - make_interval(days => sqlc.arg(days)::integer)
+WHERE sqlc.narg(feed_id)::uuid IS NULL OR feeds.id = sqlc.narg(feed_id)::uuid
+GROUP BY feeds.id
+ORDER BY feeds.name;
+
+-- name: GetRecentFeedFetches :many
+SELECT started_at, finished_at, status_code, bytes, item_count, new_posts, error
+FROM feed_fetches
+WHERE feed_id = $1
+ORDER BY started_at DESC
+LIMIT $2;
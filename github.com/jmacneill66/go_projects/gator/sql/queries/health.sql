@@ -0,0 +1,35 @@
+-- name: RecordFeedSuccess :exec
+UPDATE feeds
+SET last_success_at = now(), last_status_code = $2, last_error = NULL, consecutive_failures = 0, updated_at = now()
+WHERE id = $1;
+
+-- name: RecordFeedFailure :one
+-- Counts a failed fetch, and disables the feed once disable_after fetches in
+-- a row have failed. A disable_after of 0 never disables it.
+UPDATE feeds
+SET consecutive_failures = consecutive_failures + 1,
+    last_error = sqlc.arg(last_error),
+    last_status_code = sqlc.narg(last_status_code),
+    disabled_at = CASE
+        WHEN sqlc.arg(disable_after)::integer > 0
+            AND consecutive_failures + 1 >= sqlc.arg(disable_after)::integer THEN now()
+        ELSE disabled_at
+    END,
+    updated_at = now()
+WHERE id = sqlc.arg(id)
+RETURNING consecutive_failures, disabled_at;
+
+-- name: EnableFeed :exec
+-- Re-enables a disabled or dead feed and makes it due at once
+UPDATE feeds
+SET disabled_at = NULL, dead_at = NULL, consecutive_failures = 0, next_fetch_at = NULL, updated_at = now()
+WHERE id = $1;
+
+-- name: GetBrokenFeeds :many
+-- Lists failing, disabled and dead feeds, disabled and dead ones first
+SELECT feeds.name, feeds.url, users.name AS user_name, feeds.last_error, feeds.last_status_code,
+    feeds.consecutive_failures, feeds.last_success_at, feeds.next_fetch_at, feeds.disabled_at, feeds.dead_at
+FROM feeds
+JOIN users ON feeds.user_id = users.id
+WHERE feeds.consecutive_failures > 0 OR feeds.disabled_at IS NOT NULL OR feeds.dead_at IS NOT NULL
+ORDER BY (feeds.disabled_at IS NULL AND feeds.dead_at IS NULL), feeds.consecutive_failures DESC, feeds.name;
@@ -0,0 +1,30 @@
+-- name: RecordFeedRedirect :one
+-- Counts consecutive fetches that were permanently redirected to the same URL
+UPDATE feeds
+SET moved_count = CASE WHEN moved_to = $2 THEN moved_count + 1 ELSE 1 END,
+    moved_to = $2, updated_at = now()
+WHERE id = $1
+RETURNING moved_count;
+
+-- name: ClearFeedRedirect :exec
+UPDATE feeds
+SET moved_to = NULL, moved_count = 0, updated_at = now()
+WHERE id = $1 AND moved_to IS NOT NULL;
+
+-- name: MoveFeed :exec
+-- Rewrites the feed's URL, keeping the old one as an alias
+WITH alias AS (
+    INSERT INTO feed_url_aliases (id, created_at, feed_id, url)
+    SELECT sqlc.arg(alias_id), now(), feeds.id, feeds.url
+    FROM feeds
+    WHERE feeds.id = sqlc.arg(id)
+    ON CONFLICT (url) DO NOTHING
+)
+UPDATE feeds
+SET url = sqlc.arg(url), moved_to = NULL, moved_count = 0, updated_at = now()
+WHERE feeds.id = sqlc.arg(id);
+
+-- name: MarkFeedDead :exec
+UPDATE feeds
+SET dead_at = now(), updated_at = now()
+WHERE id = $1;
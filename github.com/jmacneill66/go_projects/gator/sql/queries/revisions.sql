@@ -0,0 +1,18 @@
+-- name: CreatePostRevision :exec
+INSERT INTO post_revisions (id, created_at, post_id, title, description, content, content_hash)
+SELECT sqlc.arg(id), sqlc.arg(created_at), posts.id, posts.title, posts.description, posts.content, posts.content_hash
+FROM posts
+WHERE posts.id = sqlc.arg(post_id);
+
+-- name: UpdatePost :exec
+UPDATE posts
+SET title = $2, url = $3, description = $4, content = $5, content_hash = $6, author = $7,
+    revised_at = now(), updated_at = now()
+WHERE id = $1;
+
+-- name: UpdatePostContent :exec
+-- Saves the content of a post stored without it, without marking the post
+-- as revised.
+UPDATE posts
+SET content = $2, content_hash = $3, updated_at = now()
+WHERE id = $1;
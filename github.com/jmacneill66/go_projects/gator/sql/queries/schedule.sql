@@ -0,0 +1,21 @@
+-- name: GetFeedPostingStats :one
+-- Summarises when the feed's 20 most recent dated posts were published
+SELECT count(*) AS post_count,
+    COALESCE(EXTRACT(EPOCH FROM max(published_at) - min(published_at)), 0)::float8 AS span_seconds,
+    COALESCE(EXTRACT(EPOCH FROM now() - max(published_at)), 0)::float8 AS since_last_seconds
+FROM (
+    SELECT published_at FROM posts
+    WHERE feed_id = $1 AND published_at IS NOT NULL
+    ORDER BY published_at DESC
+    LIMIT 20
+) recent;
+
+-- name: ScheduleNextFetch :exec
+UPDATE feeds
+SET next_fetch_at = now() + sqlc.arg(interval_seconds)::integer * interval '1 second', updated_at = now()
+WHERE id = sqlc.arg(id);
+
+-- name: UpdateFeedIntervals :exec
+UPDATE feeds
+SET min_fetch_interval_seconds = $2, max_fetch_interval_seconds = $3, updated_at = now()
+WHERE id = $1;
@@ -25,7 +25,11 @@ VALUES ($1, $2, $3, $4, $5, $6)
 RETURNING id, created_at, updated_at, name, url, user_id;
 
 -- name: GetFeedsWithUser :many
-SELECT feeds.id, feeds.created_at, feeds.updated_at, feeds.name, feeds.url, users.name AS user_name
+SELECT feeds.id, feeds.created_at, feeds.updated_at, feeds.name, feeds.url, users.name AS user_name,
+    feeds.title, feeds.description, feeds.site_url, feeds.language, feeds.image_url, feeds.generator,
+    feeds.dead_at, feeds.auth_type, feeds.headers,
+    feeds.next_fetch_at, feeds.min_fetch_interval_seconds, feeds.max_fetch_interval_seconds,
+    feeds.disabled_at
 FROM feeds
 JOIN users ON feeds.user_id = users.id;
 
@@ -45,11 +49,23 @@ FROM inserted_feed_follow
 JOIN users ON inserted_feed_follow.user_id = users.id
 JOIN feeds ON inserted_feed_follow.feed_id = feeds.id;
 
+-- name: ImportFeedFollow :exec
+INSERT INTO feed_follows (id, created_at, updated_at, user_id, feed_id, category)
+VALUES ($1, $2, $3, $4, $5, $6)
+ON CONFLICT (user_id, feed_id) DO UPDATE
+SET category = EXCLUDED.category, updated_at = EXCLUDED.updated_at;
+
 -- name: GetFeedByUrl :one
-SELECT id, name FROM feeds WHERE url = $1 LIMIT 1;
+SELECT id, name FROM feeds
+WHERE url = $1
+OR id IN (SELECT feed_id FROM feed_url_aliases WHERE feed_url_aliases.url = $1)
+ORDER BY url = $1 DESC
+LIMIT 1;
 
 -- name: GetFeedFollowsForUser :many
-SELECT feed_follows.id, feed_follows.created_at, feed_follows.updated_at, users.name AS user_name, feeds.name AS feed_name
+SELECT feed_follows.id, feed_follows.created_at, feed_follows.updated_at, users.name AS user_name, feeds.name AS feed_name,
+    feeds.url AS feed_url, feed_follows.category, feeds.title AS feed_title, feeds.description AS feed_description,
+    feeds.site_url AS feed_site_url
 FROM feed_follows
 JOIN users ON feed_follows.user_id = users.id
 JOIN feeds ON feed_follows.feed_id = feeds.id
@@ -58,29 +74,113 @@ WHERE users.name = $1;
 -- name: DeleteFeedFollow :exec
 DELETE FROM feed_follows 
 WHERE user_id = (SELECT id FROM users WHERE users.name = $1)
-AND feed_id = (SELECT id FROM feeds WHERE url = $2);
+AND feed_id IN (
+    SELECT id FROM feeds WHERE url = $2
+    UNION
+    SELECT feed_id FROM feed_url_aliases WHERE feed_url_aliases.url = $2
+);
 
--- name: MarkFeedFetched :exec
-UPDATE feeds 
-SET last_fetched_at = now(), updated_at = now() 
+-- name: UpdateFeedMetadata :exec
+UPDATE feeds
+SET title = $2, description = $3, site_url = $4, language = $5, image_url = $6, generator = $7,
+    updated_at = now()
 WHERE id = $1;
 
--- name: GetNextFeedToFetch :one
-SELECT id, url, name 
-FROM feeds 
-ORDER BY last_fetched_at NULLS FIRST, updated_at ASC 
-LIMIT 1;
+-- name: ClaimFeedsToFetch :many
+-- Marks up to batch_size due feeds as fetched and returns them. Rows locked
+-- by another agg are skipped, so no feed is claimed twice. Dead and disabled
+-- feeds are never fetched, and feeds pushed by a WebSub hub only need an
+-- occasional poll. A claimed feed is next due after its minimum interval,
+-- until the outcome of its fetch reschedules it. The feed's schedule before
+-- the claim is returned too, so that an unused claim can be released.
+UPDATE feeds
+SET last_fetched_at = now(),
+    next_fetch_at = now() + COALESCE(feeds.min_fetch_interval_seconds, sqlc.arg(default_min_seconds)::integer) * interval '1 second',
+    updated_at = now()
+FROM (
+    SELECT id, last_fetched_at, next_fetch_at FROM feeds
+    WHERE dead_at IS NULL AND disabled_at IS NULL
+    AND (next_fetch_at IS NULL OR next_fetch_at <= now())
+    AND (
+        NOT EXISTS (
+            SELECT 1 FROM websub_subscriptions
+            WHERE websub_subscriptions.feed_id = feeds.id
+            AND websub_subscriptions.lease_expires_at > now()
+        )
+        OR feeds.last_fetched_at < now() - interval '1 day'
+    )
+    ORDER BY next_fetch_at NULLS FIRST, last_fetched_at NULLS FIRST
+    LIMIT sqlc.arg(batch_size)
+    FOR UPDATE SKIP LOCKED
+) AS due
+WHERE feeds.id = due.id
+RETURNING feeds.id, feeds.url, feeds.name, feeds.etag, feeds.last_modified, feeds.auth_type, feeds.auth_username,
+    feeds.auth_secret_env, feeds.headers, feeds.min_fetch_interval_seconds, feeds.max_fetch_interval_seconds,
+    due.last_fetched_at AS claimed_last_fetched_at, due.next_fetch_at AS claimed_next_fetch_at;
+
+-- name: ReleaseFeedClaim :exec
+-- Restores the schedule of a claimed feed whose fetch never started, so it
+-- is due again as before.
+UPDATE feeds
+SET last_fetched_at = sqlc.arg(last_fetched_at), next_fetch_at = sqlc.arg(next_fetch_at), updated_at = now()
+WHERE id = sqlc.arg(id);
+
+-- name: UpdateFeedValidators :exec
+UPDATE feeds
+SET etag = $2, last_modified = $3, updated_at = now()
+WHERE id = $1;
+
+-- name: AdoptLegacyPost :exec
+-- Gives a post keyed by its URL the item's real key, unless another post
+-- of the feed already has that key.
+UPDATE posts
+SET guid = sqlc.arg(guid), updated_at = now()
+WHERE feed_id = sqlc.arg(feed_id) AND guid = sqlc.arg(legacy_guid)
+AND NOT EXISTS (
+    SELECT 1 FROM posts AS keyed
+    WHERE keyed.feed_id = sqlc.arg(feed_id) AND keyed.guid = sqlc.arg(guid)
+);
 
--- name: CreatePost :exec
-INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id)
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
-ON CONFLICT (url) DO NOTHING;
+-- name: FeedHasLegacyPosts :one
+-- Reports whether a feed has posts keyed by their URL that may still be
+-- adopted.
+SELECT EXISTS (
+    SELECT 1 FROM posts
+    JOIN feeds ON posts.feed_id = feeds.id
+    WHERE posts.feed_id = $1 AND posts.guid LIKE 'legacy:%' AND NOT feeds.legacy_posts_adopted
+);
+
+-- name: MarkLegacyPostsAdopted :exec
+-- Stops looking for posts keyed by their URL once a full fetch of the feed
+-- has had the chance to adopt them.
+UPDATE feeds SET legacy_posts_adopted = true
+WHERE id = $1 AND NOT legacy_posts_adopted;
+
+-- name: GetPostByGuid :one
+SELECT id, content_hash, content IS NOT NULL AS has_content FROM posts
+WHERE feed_id = $1 AND guid = $2;
+
+-- name: CreatePost :one
+INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id, content, guid, content_hash, author)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+ON CONFLICT (feed_id, guid) DO NOTHING
+RETURNING id;
 
 -- name: GetPostsForUser :many
-SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id
+SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.content, posts.guid,
+    posts.content_hash, posts.revised_at, posts.author
 FROM posts
 JOIN feed_follows ON posts.feed_id = feed_follows.feed_id
 JOIN users ON feed_follows.user_id = users.id
 WHERE users.name = $1
-ORDER BY posts.published_at DESC
-LIMIT $2;
\ No newline at end of file
+ORDER BY posts.published_at DESC NULLS LAST, posts.created_at DESC
+LIMIT $2;
+
+-- name: GetPostForUser :one
+SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.content,
+    posts.revised_at, posts.author, feeds.url AS feed_url
+FROM posts
+JOIN feeds ON posts.feed_id = feeds.id
+JOIN feed_follows ON posts.feed_id = feed_follows.feed_id
+JOIN users ON feed_follows.user_id = users.id
+WHERE users.name = $1 AND posts.id = $2;
@@ -0,0 +1,28 @@
+-- name: UpsertWebSubSubscription :one
+INSERT INTO websub_subscriptions (id, created_at, updated_at, feed_id, hub_url, topic_url, secret)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+ON CONFLICT (feed_id) DO UPDATE
+SET hub_url = EXCLUDED.hub_url, topic_url = EXCLUDED.topic_url, updated_at = EXCLUDED.updated_at
+RETURNING id, secret;
+
+-- name: GetWebSubSubscription :one
+SELECT * FROM websub_subscriptions
+WHERE id = $1;
+
+-- name: GetWebSubSubscriptionByFeed :one
+-- A subscription is renewed a day before its lease runs out, and requested
+-- again if the hub has not verified it within an hour
+SELECT id, hub_url, topic_url,
+    COALESCE(lease_expires_at < now() + interval '1 day', updated_at < now() - interval '1 hour')::bool AS renewal_due
+FROM websub_subscriptions
+WHERE feed_id = $1;
+
+-- name: VerifyWebSubSubscription :exec
+UPDATE websub_subscriptions
+SET verified_at = now(), updated_at = now(),
+    lease_expires_at = now() + sqlc.arg(lease_seconds)::int * interval '1 second'
+WHERE id = sqlc.arg(id);
+
+-- name: DeleteWebSubSubscription :exec
+DELETE FROM websub_subscriptions
+WHERE id = $1;
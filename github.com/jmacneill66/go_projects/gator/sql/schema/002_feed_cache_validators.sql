@@ -0,0 +1,10 @@
+-- +goose Up
+ALTER TABLE feeds ADD COLUMN etag TEXT NULL;
+ALTER TABLE feeds ADD COLUMN last_modified TEXT NULL;
+
+-- +goose Down
+ALTER TABLE feeds
+DROP COLUMN IF EXISTS last_modified;
+
+ALTER TABLE feeds
+DROP COLUMN IF EXISTS etag;
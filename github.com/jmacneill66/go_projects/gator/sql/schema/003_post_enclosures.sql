@@ -0,0 +1,18 @@
+-- +goose Up
+CREATE TABLE post_enclosures (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    created_at TIMESTAMP NOT NULL DEFAULT now(),
+    updated_at TIMESTAMP NOT NULL DEFAULT now(),
+    post_id UUID NOT NULL,
+    url TEXT NOT NULL,
+    length BIGINT,
+    mime_type TEXT,
+    duration_seconds INTEGER,
+    episode INTEGER,
+    image_url TEXT,
+    FOREIGN KEY (post_id) REFERENCES posts(id) ON DELETE CASCADE,
+    UNIQUE (post_id, url) -- Prevents duplicate enclosures
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS post_enclosures;
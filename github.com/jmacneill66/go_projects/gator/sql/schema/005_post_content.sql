@@ -0,0 +1,6 @@
+-- +goose Up
+ALTER TABLE posts ADD COLUMN content TEXT NULL;
+
+-- +goose Down
+ALTER TABLE posts
+DROP COLUMN IF EXISTS content;
@@ -0,0 +1,23 @@
+-- +goose Up
+ALTER TABLE posts ADD COLUMN guid TEXT NULL;
+
+-- Existing posts are keyed by their URL until the scraper sees them again
+-- and replaces the key with the item's real guid
+UPDATE posts SET guid = 'legacy:' || url;
+
+ALTER TABLE posts ALTER COLUMN guid SET NOT NULL;
+ALTER TABLE posts DROP CONSTRAINT IF EXISTS posts_url_key;
+ALTER TABLE posts ADD CONSTRAINT posts_feed_id_guid_key UNIQUE (feed_id, guid);
+
+-- +goose Down
+ALTER TABLE posts DROP CONSTRAINT IF EXISTS posts_feed_id_guid_key;
+
+-- Keep the oldest copy of each URL so it can be unique again
+DELETE FROM posts a
+USING posts b
+WHERE a.url = b.url
+AND (a.created_at, a.id) > (b.created_at, b.id);
+
+ALTER TABLE posts ADD CONSTRAINT posts_url_key UNIQUE (url);
+ALTER TABLE posts
+DROP COLUMN IF EXISTS guid;
@@ -0,0 +1,29 @@
+-- +goose Up
+ALTER TABLE posts ADD COLUMN content_hash TEXT NULL;
+ALTER TABLE posts ADD COLUMN revised_at TIMESTAMP NULL;
+
+-- Hash existing posts the same way the scraper does: the SHA-256 of the
+-- title, description and content separated by newlines
+UPDATE posts SET content_hash = encode(sha256(convert_to(
+    title || E'\n' || coalesce(description, '') || E'\n' || coalesce(content, ''),
+    'UTF8')), 'hex');
+
+ALTER TABLE posts ALTER COLUMN content_hash SET NOT NULL;
+
+CREATE TABLE post_revisions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    created_at TIMESTAMP NOT NULL DEFAULT now(),
+    post_id UUID NOT NULL,
+    title TEXT NOT NULL,
+    description TEXT,
+    content TEXT,
+    content_hash TEXT NOT NULL,
+    FOREIGN KEY (post_id) REFERENCES posts(id) ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS post_revisions;
+ALTER TABLE posts
+DROP COLUMN IF EXISTS revised_at;
+ALTER TABLE posts
+DROP COLUMN IF EXISTS content_hash;
@@ -0,0 +1,16 @@
+-- +goose Up
+ALTER TABLE feeds ADD COLUMN title TEXT NULL;
+ALTER TABLE feeds ADD COLUMN description TEXT NULL;
+ALTER TABLE feeds ADD COLUMN site_url TEXT NULL;
+ALTER TABLE feeds ADD COLUMN language TEXT NULL;
+ALTER TABLE feeds ADD COLUMN image_url TEXT NULL;
+ALTER TABLE feeds ADD COLUMN generator TEXT NULL;
+
+-- +goose Down
+ALTER TABLE feeds
+DROP COLUMN IF EXISTS generator,
+DROP COLUMN IF EXISTS image_url,
+DROP COLUMN IF EXISTS language,
+DROP COLUMN IF EXISTS site_url,
+DROP COLUMN IF EXISTS description,
+DROP COLUMN IF EXISTS title;
@@ -0,0 +1,16 @@
+-- +goose Up
+CREATE TABLE websub_subscriptions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    created_at TIMESTAMP NOT NULL DEFAULT now(),
+    updated_at TIMESTAMP NOT NULL DEFAULT now(),
+    feed_id UUID NOT NULL UNIQUE, -- One subscription per feed
+    hub_url TEXT NOT NULL,
+    topic_url TEXT NOT NULL,
+    secret TEXT NOT NULL,
+    verified_at TIMESTAMP,
+    lease_expires_at TIMESTAMP,
+    FOREIGN KEY (feed_id) REFERENCES feeds(id) ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS websub_subscriptions;
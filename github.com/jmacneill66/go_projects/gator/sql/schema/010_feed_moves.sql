@@ -0,0 +1,19 @@
+-- +goose Up
+ALTER TABLE feeds ADD COLUMN moved_to TEXT NULL;
+ALTER TABLE feeds ADD COLUMN moved_count INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE feeds ADD COLUMN dead_at TIMESTAMP NULL;
+
+CREATE TABLE feed_url_aliases (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    created_at TIMESTAMP NOT NULL DEFAULT now(),
+    feed_id UUID NOT NULL,
+    url TEXT NOT NULL UNIQUE,
+    FOREIGN KEY (feed_id) REFERENCES feeds(id) ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS feed_url_aliases;
+ALTER TABLE feeds
+DROP COLUMN IF EXISTS dead_at,
+DROP COLUMN IF EXISTS moved_count,
+DROP COLUMN IF EXISTS moved_to;
@@ -0,0 +1,15 @@
+-- +goose Up
+-- Secrets are never stored: auth_secret_env names the environment variable
+-- holding the password or token, and header values may reference
+-- environment variables as ${NAME}
+ALTER TABLE feeds ADD COLUMN auth_type TEXT NULL CHECK (auth_type IN ('basic', 'bearer'));
+ALTER TABLE feeds ADD COLUMN auth_username TEXT NULL;
+ALTER TABLE feeds ADD COLUMN auth_secret_env TEXT NULL;
+ALTER TABLE feeds ADD COLUMN headers JSONB NOT NULL DEFAULT '{}';
+
+-- +goose Down
+ALTER TABLE feeds
+DROP COLUMN IF EXISTS headers,
+DROP COLUMN IF EXISTS auth_secret_env,
+DROP COLUMN IF EXISTS auth_username,
+DROP COLUMN IF EXISTS auth_type;
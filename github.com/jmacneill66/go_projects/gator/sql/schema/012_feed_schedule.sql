@@ -0,0 +1,15 @@
+-- +goose Up
+-- next_fetch_at is when agg should next fetch the feed, NULL meaning as soon
+-- as possible. The interval bounds are in seconds, NULL meaning the default.
+ALTER TABLE feeds ADD COLUMN next_fetch_at TIMESTAMP NULL;
+ALTER TABLE feeds ADD COLUMN min_fetch_interval_seconds INTEGER NULL CHECK (min_fetch_interval_seconds > 0);
+ALTER TABLE feeds ADD COLUMN max_fetch_interval_seconds INTEGER NULL CHECK (max_fetch_interval_seconds > 0);
+
+CREATE INDEX feeds_next_fetch_at_idx ON feeds (next_fetch_at);
+
+-- +goose Down
+DROP INDEX IF EXISTS feeds_next_fetch_at_idx;
+ALTER TABLE feeds
+DROP COLUMN IF EXISTS max_fetch_interval_seconds,
+DROP COLUMN IF EXISTS min_fetch_interval_seconds,
+DROP COLUMN IF EXISTS next_fetch_at;
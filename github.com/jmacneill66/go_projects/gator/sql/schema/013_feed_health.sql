@@ -0,0 +1,16 @@
+-- +goose Up
+-- Outcome of the most recent fetches. A feed is disabled after too many
+-- failed fetches in a row, and is not fetched again until re-enabled.
+ALTER TABLE feeds ADD COLUMN last_error TEXT NULL;
+ALTER TABLE feeds ADD COLUMN last_status_code INTEGER NULL;
+ALTER TABLE feeds ADD COLUMN consecutive_failures INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE feeds ADD COLUMN last_success_at TIMESTAMP NULL;
+ALTER TABLE feeds ADD COLUMN disabled_at TIMESTAMP NULL;
+
+-- +goose Down
+ALTER TABLE feeds
+DROP COLUMN IF EXISTS disabled_at,
+DROP COLUMN IF EXISTS last_success_at,
+DROP COLUMN IF EXISTS consecutive_failures,
+DROP COLUMN IF EXISTS last_status_code,
+DROP COLUMN IF EXISTS last_error;
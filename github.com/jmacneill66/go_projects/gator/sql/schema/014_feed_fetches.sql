@@ -0,0 +1,20 @@
+-- +goose Up
+-- One row per attempt to fetch a feed. error is NULL when it succeeded.
+CREATE TABLE feed_fetches (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    feed_id UUID NOT NULL,
+    started_at TIMESTAMP NOT NULL,
+    finished_at TIMESTAMP NOT NULL,
+    status_code INTEGER,
+    bytes BIGINT NOT NULL DEFAULT 0,
+    item_count INTEGER NOT NULL DEFAULT 0,
+    new_posts INTEGER NOT NULL DEFAULT 0,
+    error TEXT,
+    FOREIGN KEY (feed_id) REFERENCES feeds(id) ON DELETE CASCADE
+);
+
+CREATE INDEX feed_fetches_feed_id_started_at_idx ON feed_fetches (feed_id, started_at DESC);
+CREATE INDEX feed_fetches_started_at_idx ON feed_fetches (started_at);
+
+-- +goose Down
+DROP TABLE IF EXISTS feed_fetches;
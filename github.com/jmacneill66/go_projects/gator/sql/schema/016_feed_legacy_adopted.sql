@@ -0,0 +1,10 @@
+-- +goose Up
+-- Set after the first full fetch of a feed that has posts still keyed by
+-- their URL. Every post the feed still lists has been given its real key by
+-- then, and those it no longer lists never will be, so the scraper stops
+-- looking for them.
+ALTER TABLE feeds ADD COLUMN legacy_posts_adopted BOOLEAN NOT NULL DEFAULT false;
+
+-- +goose Down
+ALTER TABLE feeds
+DROP COLUMN IF EXISTS legacy_posts_adopted;